@@ -0,0 +1,206 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrMissingValue indicates a flag at the end of the arguments that needs a value but has none
+	ErrMissingValue = errors.New("flag needs a value")
+	// ErrNotBool indicates a --no-flag whose struct member isn't a bool
+	ErrNotBool = errors.New("--no- prefix is only allowed on boolean flags")
+	// ErrBadArgTag indicates an arg tag that is neither a position number nor "rest", or "rest" on a member that isn't a slice
+	ErrBadArgTag = errors.New(`arg tag must be a position number, or "rest" on a slice`)
+)
+
+// FromArguments extracts settings from a list of arguments such as those supplied on the command line.
+// Each key.path is a period '.' or underscore '_' separated path to the struct member, or an entry of a map member
+// such as limits.cpu, and may be given as
+//	key.path=value
+//	--key.path=value
+//	--key.path value
+//	--flag, --no-flag     sets a bool member true or false
+// A single dash works the same as two. Arguments after a "--" terminator, and other arguments
+// without a dash or an '=', are positional arguments, which are bound to struct members as ParseArguments
+// describes. Flags that match no struct member are errors, except --help and -h, which stop parsing and return ErrHelp.
+// Every argument is attempted, and failures are returned together as Errors.
+func FromArguments(args []string, v interface{}) error {
+	_, err := applyArguments(args, v, nil)
+	return err
+}
+
+// ParseArguments extracts settings from args exactly like FromArguments, and also returns the
+// positional arguments that weren't bound to a struct member, in their original order.
+// Members tagged `arg:"0"`, `arg:"1"` and so on take the positional argument at that position,
+// and a slice member tagged `arg:"rest"` takes every one after the highest numbered position.
+//	type cfg struct {
+//		Command string   `arg:"0"`
+//		Files   []string `arg:"rest"`
+//	}
+func ParseArguments(args []string, v interface{}) ([]string, error) {
+	return applyArguments(args, v, nil)
+}
+
+// positional is an argument that isn't a setting, along with where it appeared.
+type positional struct {
+	value string
+	index int
+}
+
+func applyArguments(args []string, v interface{}, p Provenance) ([]string, error) {
+	root, err := rootScope(v)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []scope{root}
+
+	var errs Errors
+	var pos []positional
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			for j := i + 1; j < len(args); j++ {
+				pos = append(pos, positional{args[j], j})
+			}
+			break
+		}
+
+		flag := strings.HasPrefix(arg, "-") && arg != "-"
+		text := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		key, val, hasValue := text, "", false
+		if eq := strings.Index(text, "="); eq >= 0 {
+			key, val, hasValue = text[:eq], text[eq+1:], true
+		}
+		if !flag && !hasValue {
+			if cmd, ok := scopes[len(scopes)-1].command(arg, i); ok && len(pos) == 0 {
+				// the section itself records the choice, so later checks can skip the ones not chosen
+				scopes = append(scopes, cmd)
+				p.Set(cmd.path, Origin{Layer: LayerArguments, Name: arg, Index: i})
+				continue
+			}
+			pos = append(pos, positional{arg, i})
+			continue
+		}
+
+		// transform period separators into underscores
+		key = strings.ReplaceAll(key, "_", ".")
+
+		// find struct member matching key path
+		value, sf, path, store, err := resolveScoped(scopes, key)
+		if err == ErrUnknownField && flag && !hasValue && (key == "help" || key == "h") {
+			return nil, ErrHelp
+		}
+		if err == ErrUnknownField && flag && !hasValue && strings.HasPrefix(key, "no-") {
+			if value, sf, path, store, err = resolveScoped(scopes, key[3:]); err == nil && value.Kind() != reflect.Bool {
+				err = ErrNotBool
+			}
+			hasValue, val = true, "false"
+		}
+		if err != nil {
+			errs.add(LayerArguments, key, arg, err)
+			continue
+		}
+
+		name, start := arg, i
+		switch {
+		case hasValue:
+		case value.Kind() == reflect.Bool:
+			val = "true"
+		case i+1 < len(args):
+			i++
+			val = args[i]
+			name = arg + " " + val
+		default:
+			errs.add(LayerArguments, path, arg, ErrMissingValue)
+			continue
+		}
+
+		// unmarshal the string into the struct field
+		err = unmarshalField(val, value, sf)
+		if err != nil {
+			errs.add(LayerArguments, path, name, err)
+			continue
+		}
+		store()
+		p.setValue(path, Origin{Layer: LayerArguments, Name: name, Index: start}, val, value.Type())
+	}
+
+	chosen := scopes[len(scopes)-1]
+	bindCommand(root, chosen, p)
+	rest, err := bindPositional(pos, chosen, p)
+	errs.add(LayerArguments, "", "", err)
+	return rest, errs.err()
+}
+
+// bindPositional sets the members of the chosen scope tagged with arg to their positional arguments,
+// and returns the values of those left over.
+func bindPositional(pos []positional, s scope, p Provenance) ([]string, error) {
+	var errs Errors
+	var numbered []field
+	var rest *field
+	last := -1
+	err := walkFields(s.pointer(), func(f field) error {
+		tag, ok := f.sf.Tag.Lookup("arg")
+		if !ok || tag == "cmd" || inCommand(f) {
+			return nil
+		}
+		if tag == "rest" && f.value.Kind() == reflect.Slice {
+			rest = &f
+			return nil
+		}
+		n, err := strconv.Atoi(tag)
+		if err != nil || n < 0 {
+			errs.add(LayerArguments, s.join(f.Path()), tag, ErrBadArgTag)
+			return nil
+		}
+		if n > last {
+			last = n
+		}
+		numbered = append(numbered, f)
+		return nil
+	})
+	errs.add(LayerArguments, "", "", err)
+
+	used := make([]bool, len(pos))
+	for _, f := range numbered {
+		n, _ := strconv.Atoi(f.sf.Tag.Get("arg"))
+		if n >= len(pos) {
+			continue
+		}
+		used[n] = true
+		if err := unmarshalField(pos[n].value, f.value, f.sf); err != nil {
+			errs.add(LayerArguments, s.join(f.Path()), pos[n].value, err)
+			continue
+		}
+		p.setValue(s.join(f.Path()), Origin{Layer: LayerArguments, Name: pos[n].value, Index: pos[n].index}, pos[n].value, f.value.Type())
+	}
+
+	if rest != nil && last+1 < len(pos) {
+		tail := pos[last+1:]
+		slice := reflect.MakeSlice(rest.value.Type(), len(tail), len(tail))
+		failed := false
+		for i, a := range tail {
+			used[last+1+i] = true
+			if err := UnmarshalValue(a.value, slice.Index(i)); err != nil {
+				errs.add(LayerArguments, fmt.Sprintf("%s[%d]", s.join(rest.Path()), i), a.value, err)
+				failed = true
+			}
+		}
+		if !failed {
+			rest.value.Set(slice)
+			p.Set(s.join(rest.Path()), Origin{Layer: LayerArguments, Name: tail[0].value, Index: tail[0].index})
+		}
+	}
+
+	var left []string
+	for i, a := range pos {
+		if !used[i] {
+			left = append(left, a.value)
+		}
+	}
+	return left, errs.err()
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromArgumentsFlags(t *testing.T) {
+	c := TestArguments{}
+	c.Sub.Enabled = true
+	p := Provenance{}
+	_, err := applyArguments([]string{"--address=http://example.com", "-timeout", "5m", "--sub.level", "-3", "--no-sub.enabled"}, &c, p)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://example.com", c.Address)
+	assert.Equal(t, 5*time.Minute, c.Timeout)
+	assert.Equal(t, -3, c.Sub.Level)
+	assert.Equal(t, false, c.Sub.Enabled)
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "-timeout 5m", Index: 1}, p["Timeout"])
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "--sub.level -3", Index: 3}, p["Sub.Level"])
+
+	c = TestArguments{}
+	err = FromArguments([]string{"--sub.enabled", "--sub_level=4"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, true, c.Sub.Enabled)
+	assert.Equal(t, 4, c.Sub.Level)
+}
+
+func TestFromArgumentsTerminator(t *testing.T) {
+	c := TestArguments{}
+	err := FromArguments([]string{"input.txt", "-", "--", "--address=http://example.com", "--bogus"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, "", c.Address)
+}
+
+func TestFromArgumentsBadFlags(t *testing.T) {
+	c := TestArguments{}
+	err := FromArguments([]string{"--bogus", "--no-address", "--no-bogus", "--timeout"}, &c)
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 4)
+	assert.Equal(t, &SourceError{Layer: LayerArguments, Path: "bogus", Input: "--bogus", Err: ErrUnknownField}, errs[0])
+	assert.Equal(t, &SourceError{Layer: LayerArguments, Path: "no-address", Input: "--no-address", Err: ErrNotBool}, errs[1])
+	assert.Equal(t, &SourceError{Layer: LayerArguments, Path: "no-bogus", Input: "--no-bogus", Err: ErrUnknownField}, errs[2])
+	assert.Equal(t, &SourceError{Layer: LayerArguments, Path: "Timeout", Input: "--timeout", Err: ErrMissingValue}, errs[3])
+}
+
+type TestPositional struct {
+	Verbose bool
+	Command string   `arg:"0"`
+	Count   int      `arg:"1"`
+	Files   []string `arg:"rest"`
+}
+
+func TestParseArguments(t *testing.T) {
+	c := TestPositional{}
+	p := Provenance{}
+	rest, err := applyArguments([]string{"copy", "--verbose", "3", "a.txt", "--", "-b.txt"}, &c, p)
+	assert.Nil(t, err)
+	assert.Nil(t, rest)
+	assert.Equal(t, TestPositional{Verbose: true, Command: "copy", Count: 3, Files: []string{"a.txt", "-b.txt"}}, c)
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "3", Index: 2}, p["Count"])
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "a.txt", Index: 3}, p["Files"])
+}
+
+func TestParseArgumentsLeftover(t *testing.T) {
+	c := TestArguments{}
+	rest, err := ParseArguments([]string{"in.txt", "--timeout", "1m", "out.txt", "--", "--address=x"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"in.txt", "out.txt", "--address=x"}, rest)
+	assert.Equal(t, time.Minute, c.Timeout)
+	assert.Equal(t, "", c.Address)
+
+	type cmd struct {
+		Command string `arg:"0"`
+	}
+	cc := cmd{}
+	rest, err = ParseArguments([]string{"run", "fast"}, &cc)
+	assert.Nil(t, err)
+	assert.Equal(t, "run", cc.Command)
+	assert.Equal(t, []string{"fast"}, rest)
+}
+
+// Load and custom loaders hand back the leftover arguments when asked
+func TestLoadRemainingArgs(t *testing.T) {
+	os.Args = []string{"test", "in.txt", "--timeout", "1m", "out.txt"}
+
+	var rest []string
+	c := TestArguments{}
+	err := LoadE("bogus_file_name.yml", &c, WithRemainingArgs(&rest))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"in.txt", "out.txt"}, rest)
+	assert.Equal(t, time.Minute, c.Timeout)
+
+	c = TestArguments{}
+	err = NewLoader(Arguments([]string{"a", "--", "-b"}, WithRemainingArgs(&rest))).Load(&c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "-b"}, rest)
+}
+
+func TestParseArgumentsBadPositional(t *testing.T) {
+	type bad struct {
+		Count int    `arg:"0"`
+		Name  string `arg:"rest"`
+	}
+	c := bad{}
+	_, err := ParseArguments([]string{"many"}, &c)
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, ErrBadArgTag, errs[0].(*SourceError).Err)
+	assert.Equal(t, "Count", errs[1].(*SourceError).Path)
+}
+
+func TestFromArgumentsInlineStruct(t *testing.T) {
+	c := TestArguments{}
+	err := FromArguments([]string{"sub={enabled:true,level:5}"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, true, c.Sub.Enabled)
+	assert.Equal(t, 5, c.Sub.Level)
+
+	c = TestArguments{}
+	err = FromArguments([]string{"--sub", "level=6;enabled=true"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, true, c.Sub.Enabled)
+	assert.Equal(t, 6, c.Sub.Level)
+}
+
+type TestPointers struct {
+	Limit *int
+	Sub   *sub
+	Deep  *struct {
+		Sub *sub
+	}
+}
+
+func TestFromArgumentsPointers(t *testing.T) {
+	c := TestPointers{}
+	err := FromArguments([]string{"limit=5", "sub.level=3", "--deep.sub.enabled"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, *c.Limit)
+	assert.Equal(t, 3, c.Sub.Level)
+	assert.True(t, c.Deep.Sub.Enabled)
+
+	err = FromArguments([]string{"limit=null", "sub=null"}, &c)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Limit)
+	assert.Nil(t, c.Sub)
+
+	// pointers aren't left allocated by a key that doesn't resolve
+	c = TestPointers{}
+	err = FromArguments([]string{"deep.sub.bogus=1"}, &c)
+	assert.NotNil(t, err)
+	assert.Nil(t, c.Deep)
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ErrBadTime indicates a time that is neither RFC 3339 nor a plain date
+var ErrBadTime = errors.New("time must be RFC 3339, such as 2006-01-02T15:04:05Z07:00, or a date such as 2006-01-02")
+
+// timeLayouts are the formats accepted for a time.Time, most precise first.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// The standard library types that UnmarshalValue converts without needing a registered decoder.
+// Each one is registered for both the type and a pointer to it, so nil pointer members work too.
+func init() {
+	builtinDecoder(reflect.TypeOf((*time.Time)(nil)), func(s string) (interface{}, error) {
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return &t, nil
+			}
+		}
+		return nil, ErrBadTime
+	})
+	builtinDecoder(reflect.TypeOf((*time.Location)(nil)), func(s string) (interface{}, error) {
+		return time.LoadLocation(s)
+	})
+	builtinDecoder(reflect.TypeOf((*net.IP)(nil)), func(s string) (interface{}, error) {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: s}
+		}
+		return &ip, nil
+	})
+	builtinDecoder(reflect.TypeOf((*net.IPNet)(nil)), func(s string) (interface{}, error) {
+		ip, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		// keep the address as written, such as 10.1.2.3/8, rather than just the network
+		ipNet.IP = ip
+		return ipNet, nil
+	})
+	builtinDecoder(reflect.TypeOf((*url.URL)(nil)), func(s string) (interface{}, error) {
+		return url.Parse(s)
+	})
+	builtinDecoder(reflect.TypeOf((*regexp.Regexp)(nil)), func(s string) (interface{}, error) {
+		return regexp.Compile(s)
+	})
+	builtinDecoder(reflect.TypeOf((*os.FileMode)(nil)), func(s string) (interface{}, error) {
+		m, err := strconv.ParseUint(s, 8, 32)
+		if err != nil {
+			return nil, err
+		}
+		mode := os.FileMode(m)
+		return &mode, nil
+	})
+	builtinDecoder(reflect.TypeOf((*big.Int)(nil)), func(s string) (interface{}, error) {
+		i, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid big.Int %q", s)
+		}
+		return i, nil
+	})
+	builtinDecoder(reflect.TypeOf((*big.Float)(nil)), func(s string) (interface{}, error) {
+		f, _, err := big.ParseFloat(s, 10, 0, big.ToNearestEven)
+		return f, err
+	})
+}
+
+// builtinDecoder registers fn, which returns a value of pointer type ptr, for ptr and for the type it points to.
+func builtinDecoder(ptr reflect.Type, fn DecoderFunc) {
+	decoders[ptr] = fn
+	decoders[ptr.Elem()] = func(s string) (interface{}, error) {
+		v, err := fn(s)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(v).Elem().Interface(), nil
+	}
+}
+
+// stringTypes are the builtin types written with their String method.
+var stringTypes = map[reflect.Type]bool{
+	reflect.TypeOf(url.URL{}):       true,
+	reflect.TypeOf(time.Location{}): true,
+	reflect.TypeOf(net.IPNet{}):     true,
+	reflect.TypeOf(regexp.Regexp{}): true,
+}
+
+// textOf returns the text that UnmarshalValue reads back into a value like rv, for values that are written
+// as a string rather than as a number, bool, list, or table. The second result is false for all other values.
+func textOf(rv reflect.Value) (string, bool) {
+	if !rv.IsValid() || !rv.CanInterface() {
+		return "", false
+	}
+	switch t := rv.Interface().(type) {
+	case time.Duration:
+		return t.String(), true
+	case time.Time:
+		return t.Format(time.RFC3339Nano), true
+	case os.FileMode:
+		return fmt.Sprintf("%#o", uint32(t)), true
+	}
+
+	// copy into a pointer so that methods declared on the pointer, such as big.Int's, are found
+	ptr := reflect.New(rv.Type())
+	ptr.Elem().Set(rv)
+	if stringTypes[rv.Type()] {
+		return ptr.Interface().(fmt.Stringer).String(), true
+	}
+	if tm, ok := ptr.Interface().(encoding.TextMarshaler); ok {
+		if text, err := tm.MarshalText(); err == nil {
+			return string(text), true
+		}
+	}
+	return "", false
+}
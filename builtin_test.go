@@ -0,0 +1,129 @@
+package config
+
+import (
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestBuiltins struct {
+	Started  time.Time
+	Birthday time.Time
+	Zone     *time.Location
+	IP       net.IP
+	Subnet   net.IPNet
+	Home     url.URL
+	Mirror   *url.URL
+	Pattern  *regexp.Regexp
+	Mode     os.FileMode
+	Huge     *big.Int
+	Ratio    big.Float
+	Cache    ByteSize
+}
+
+func TestUnmarshalBuiltins(t *testing.T) {
+	cfg := TestBuiltins{}
+	err := FromArguments([]string{
+		"started=2021-03-04T05:06:07.5Z",
+		"birthday=1999-12-31",
+		"zone=UTC",
+		"ip=10.1.2.3",
+		"subnet=10.1.2.3/8",
+		"home=https://example.com/a?b=c",
+		"mirror=ftp://mirror.example.com/",
+		"pattern=^a+b$",
+		"mode=0640",
+		"huge=123456789012345678901234567890",
+		"ratio=0.125",
+		"cache=10MiB",
+	}, &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2021, 3, 4, 5, 6, 7, 5e8, time.UTC), cfg.Started)
+	assert.Equal(t, time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), cfg.Birthday)
+	assert.Equal(t, "UTC", cfg.Zone.String())
+	assert.Equal(t, "10.1.2.3", cfg.IP.String())
+	assert.Equal(t, "10.1.2.3/8", cfg.Subnet.String())
+	assert.Equal(t, "example.com", cfg.Home.Host)
+	assert.Equal(t, "mirror.example.com", cfg.Mirror.Host)
+	assert.True(t, cfg.Pattern.MatchString("aab"))
+	assert.Equal(t, os.FileMode(0640), cfg.Mode)
+	assert.Equal(t, "123456789012345678901234567890", cfg.Huge.String())
+	assert.Equal(t, "0.125", cfg.Ratio.String())
+	assert.Equal(t, 10*MiB, cfg.Cache)
+}
+
+func TestUnmarshalBuiltinsBad(t *testing.T) {
+	var tm time.Time
+	assert.Equal(t, ErrBadTime, Unmarshal("yesterday", &tm))
+	var ip net.IP
+	assert.NotNil(t, Unmarshal("10.1.2", &ip))
+	var mode os.FileMode
+	assert.NotNil(t, Unmarshal("0999", &mode))
+	var huge big.Int
+	assert.NotNil(t, Unmarshal("12ab", &huge))
+	var re *regexp.Regexp
+	assert.NotNil(t, Unmarshal("a(", &re))
+}
+
+// every builtin type is written by ToYaml as text that FromYaml reads back
+func TestBuiltinsRoundTrip(t *testing.T) {
+	cfg := TestBuiltins{}
+	err := FromArguments([]string{
+		"started=2021-03-04T05:06:07.5+02:00", "birthday=1999-12-31", "zone=UTC", "ip=::1", "subnet=192.168.0.0/16",
+		"home=https://example.com/a?b=c", "mirror=ftp://mirror.example.com/", "pattern=^a+b$", "mode=0755",
+		"huge=-98765432109876543210", "ratio=2.5", "cache=1500",
+	}, &cfg)
+	assert.Nil(t, err)
+
+	yml, err := ToYaml(&cfg)
+	assert.Nil(t, err)
+	assert.Contains(t, yml, "mode: \"0755\"\n")
+	assert.Contains(t, yml, "cache: 1500B\n")
+
+	back := TestBuiltins{}
+	err = FromYaml([]byte(yml), &back)
+	assert.Nil(t, err)
+	assert.True(t, cfg.Started.Equal(back.Started))
+	assert.Equal(t, cfg.Birthday, back.Birthday)
+	assert.Equal(t, cfg.Zone.String(), back.Zone.String())
+	assert.Equal(t, cfg.IP, back.IP)
+	assert.Equal(t, cfg.Subnet.String(), back.Subnet.String())
+	assert.Equal(t, cfg.Home, back.Home)
+	assert.Equal(t, cfg.Mirror, back.Mirror)
+	assert.Equal(t, cfg.Pattern.String(), back.Pattern.String())
+	assert.Equal(t, cfg.Mode, back.Mode)
+	assert.Equal(t, 0, cfg.Huge.Cmp(back.Huge))
+	assert.Equal(t, 0, cfg.Ratio.Cmp(&back.Ratio))
+	assert.Equal(t, cfg.Cache, back.Cache)
+}
+
+// YAML aliases and merge keys are followed
+func TestFromYamlAliases(t *testing.T) {
+	yml := `
+base: &base
+  address: http://base.com/
+  timeout: 1m
+sub:
+  <<: *base
+  timeout: 2m
+`
+	type server struct {
+		Address string
+		Timeout time.Duration
+	}
+	cfg := struct {
+		Base server
+		Sub  server
+	}{}
+	err := FromYaml([]byte(yml), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://base.com/", cfg.Sub.Address)
+	assert.Equal(t, 2*time.Minute, cfg.Sub.Timeout)
+	assert.Equal(t, time.Minute, cfg.Base.Timeout)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrBadByteSize indicates text that isn't a number of bytes with an optional unit such as KB or MiB
+var ErrBadByteSize = errors.New("byte size must be a number with an optional unit such as 512, 10MB, or 1.5GiB")
+
+// ByteSize is a number of bytes, set from text such as "512", "10MB", or "1.5GiB".
+// Decimal units (kB, MB, GB, TB, PB) are powers of 1000 and binary units (KiB, MiB, GiB, TiB, PiB)
+// are powers of 1024. Units ignore case, and the B may be left off, so "10m" is 10MB.
+type ByteSize uint64
+
+// Binary byte size units
+const (
+	KiB ByteSize = 1 << (10 * (iota + 1))
+	MiB
+	GiB
+	TiB
+	PiB
+)
+
+// Decimal byte size units
+const (
+	KB ByteSize = 1000
+	MB          = 1000 * KB
+	GB          = 1000 * MB
+	TB          = 1000 * GB
+	PB          = 1000 * TB
+)
+
+// byteUnits are tried in order when writing a ByteSize, so the largest exact unit is used.
+var byteUnits = []struct {
+	name string
+	size ByteSize
+}{
+	{"PiB", PiB}, {"PB", PB}, {"TiB", TiB}, {"TB", TB}, {"GiB", GiB}, {"GB", GB},
+	{"MiB", MiB}, {"MB", MB}, {"KiB", KiB}, {"kB", KB},
+}
+
+// String writes the size in the largest unit that divides it exactly, such as "10MiB", or in bytes, such as "1500B".
+func (b ByteSize) String() string {
+	for _, u := range byteUnits {
+		if b >= u.size && b%u.size == 0 {
+			return strconv.FormatUint(uint64(b/u.size), 10) + u.name
+		}
+	}
+	return strconv.FormatUint(uint64(b), 10) + "B"
+}
+
+// MarshalText writes the size like String, so it reads back unchanged.
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText parses a size such as "512", "10MB", or "1.5GiB".
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+	mult := ByteSize(1)
+	if unit != "" && unit != "b" {
+		unit = strings.TrimSuffix(unit, "b")
+		found := false
+		for _, u := range byteUnits {
+			if strings.TrimSuffix(strings.ToLower(u.name), "b") == unit {
+				mult, found = u.size, true
+				break
+			}
+		}
+		if !found {
+			return ErrBadByteSize
+		}
+	}
+
+	if n, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if n > math.MaxUint64/uint64(mult) {
+			return ErrBadByteSize
+		}
+		*b = ByteSize(n) * mult
+		return nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f*float64(mult) >= math.MaxUint64 {
+		return ErrBadByteSize
+	}
+	*b = ByteSize(f * float64(mult))
+	return nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteSizeUnmarshal(t *testing.T) {
+	cases := map[string]ByteSize{
+		"0":       0,
+		"512":     512,
+		"512B":    512,
+		"10MiB":   10 * MiB,
+		"10mib":   10 * MiB,
+		"10MB":    10 * MB,
+		"10m":     10 * MB,
+		"1.5GiB":  GiB + 512*MiB,
+		"2 kB":    2 * KB,
+		"3KiB":    3 * KiB,
+		"1PB":     PB,
+		"4TiB":    4 * TiB,
+		" 7GB   ": 7 * GB,
+	}
+	for text, want := range cases {
+		var b ByteSize
+		assert.Nil(t, Unmarshal(text, &b), text)
+		assert.Equal(t, want, b, text)
+	}
+
+	var b ByteSize
+	assert.Equal(t, ErrBadByteSize, Unmarshal("10XB", &b))
+	assert.Equal(t, ErrBadByteSize, Unmarshal("MiB", &b))
+	assert.Equal(t, ErrBadByteSize, Unmarshal("20000000PiB", &b))
+}
+
+func TestByteSizeString(t *testing.T) {
+	assert.Equal(t, "0B", ByteSize(0).String())
+	assert.Equal(t, "1500B", ByteSize(1500).String())
+	assert.Equal(t, "10MiB", (10 * MiB).String())
+	assert.Equal(t, "3MB", (3 * MB).String())
+	assert.Equal(t, "1536MiB", (GiB + 512*MiB).String())
+	assert.Equal(t, "2kB", (2 * KB).String())
+}
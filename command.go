@@ -0,0 +1,131 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// scope is the struct that command line arguments currently apply to:
+// the whole configuration, or the section of a subcommand named on the command line.
+type scope struct {
+	value reflect.Value
+	path  string
+	names []string
+	index int
+}
+
+// rootScope returns the scope for the whole configuration struct pointed to by v.
+func rootScope(v interface{}) (scope, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return scope{}, ErrInvalidType
+	}
+	if rv.Elem().Kind() != reflect.Struct {
+		return scope{}, ErrInvalidType
+	}
+	return scope{value: rv.Elem()}, nil
+}
+
+// command returns the scope of the subcommand section named name directly inside s, if there is one,
+// remembering that it was named by argument index. Subcommand sections are struct members tagged `cmd:"name"`.
+func (s scope) command(name string, index int) (scope, bool) {
+	typ := s.value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" || !isCommand(sf) || sf.Tag.Get("cmd") != name {
+			continue
+		}
+		return scope{
+			value: s.value.Field(i),
+			path:  s.join(sf.Name),
+			names: append(append([]string{}, s.names...), name),
+			index: index,
+		}, true
+	}
+	return scope{}, false
+}
+
+// join prefixes a member path inside s with the path of s itself.
+func (s scope) join(path string) string {
+	if s.path == "" {
+		return path
+	}
+	return s.path + "." + path
+}
+
+// pointer returns a pointer to the struct of s, for walking or resolving within it.
+func (s scope) pointer() interface{} {
+	return s.value.Addr().Interface()
+}
+
+// resolveScoped finds the member named by key, trying each scope from the innermost subcommand out to the root,
+// so a subcommand's own settings take precedence over common ones of the same name.
+// Like resolveField, it also returns the struct field the key path ends in, and the function that stores map entries.
+func resolveScoped(scopes []scope, key string) (reflect.Value, reflect.StructField, string, func(), error) {
+	for i := len(scopes) - 1; i >= 0; i-- {
+		value, sf, path, store, err := resolveField(scopes[i].pointer(), key)
+		if err == ErrUnknownField && i > 0 {
+			continue
+		}
+		return value, sf, scopes[i].join(path), store, err
+	}
+	return reflect.Value{}, reflect.StructField{}, "", nil, ErrUnknownField
+}
+
+// isCommand reports whether a struct member is a subcommand section.
+func isCommand(sf reflect.StructField) bool {
+	return sf.Type.Kind() == reflect.Struct && sf.Tag.Get("cmd") != ""
+}
+
+// inCommand reports whether a member lies inside a subcommand section of the struct it was walked from.
+func inCommand(f field) bool {
+	for _, sf := range f.parents {
+		if isCommand(sf) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandChosen reports whether every subcommand section that f lies in was chosen on the command line,
+// as recorded in p under the section's own path. Members outside any section are always chosen.
+func commandChosen(f field, p Provenance) bool {
+	var path []string
+	for _, sf := range f.parents {
+		path = append(path, sf.Name)
+		if _, ok := p[joinPath(path)]; isCommand(sf) && !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// bindCommand sets root members tagged `arg:"cmd"` to the space separated names of the chosen subcommands.
+func bindCommand(root, chosen scope, p Provenance) {
+	if len(chosen.names) == 0 {
+		return
+	}
+	walkFields(root.pointer(), func(f field) error {
+		if f.sf.Tag.Get("arg") == "cmd" && f.value.Kind() == reflect.String && !inCommand(f) {
+			name := strings.Join(chosen.names, " ")
+			f.value.SetString(name)
+			p.Set(f.Path(), Origin{Layer: LayerArguments, Name: name, Index: chosen.index})
+		}
+		return nil
+	})
+}
+
+// commandKey splits the argument key of a member into the subcommands that lead to its section,
+// and its key path within that section.
+func commandKey(f field, tags ...string) ([]string, string) {
+	var names, keys []string
+	for _, sf := range f.parents {
+		if isCommand(sf) {
+			names = append(names, sf.Tag.Get("cmd"))
+			keys = nil
+			continue
+		}
+		keys = append(keys, keyName(sf, tags))
+	}
+	return names, joinPath(append(keys, keyName(f.sf, tags)))
+}
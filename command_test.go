@@ -0,0 +1,107 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestTool struct {
+	Verbose bool
+	Command string `arg:"cmd"`
+	Serve   struct {
+		Port int `default:"8080"`
+	} `cmd:"serve"`
+	Migrate struct {
+		Verbose bool
+		Target  struct {
+			Version int
+		}
+		Dir string `arg:"0"`
+	} `cmd:"migrate"`
+}
+
+func TestSubcommandArguments(t *testing.T) {
+	c := TestTool{}
+	p := Provenance{}
+	rest, err := applyArguments([]string{"--verbose", "migrate", "target.version=7", "--verbose", "db/", "extra"}, &c, p)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"extra"}, rest)
+	assert.Equal(t, "migrate", c.Command)
+	assert.Equal(t, 7, c.Migrate.Target.Version)
+	assert.True(t, c.Verbose)
+	assert.True(t, c.Migrate.Verbose)
+	assert.Equal(t, "db/", c.Migrate.Dir)
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "target.version=7", Index: 2}, p["Migrate.Target.Version"])
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "migrate", Index: 1}, p["Command"])
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "db/", Index: 4}, p["Migrate.Dir"])
+
+	// the full path still works, and the section's positional members are only bound when it is chosen
+	c = TestTool{}
+	rest, err = ParseArguments([]string{"migrate.target.version=3", "db/"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"db/"}, rest)
+	assert.Equal(t, "", c.Command)
+	assert.Equal(t, 3, c.Migrate.Target.Version)
+	assert.Equal(t, "", c.Migrate.Dir)
+
+	// a command name after a positional argument is just another positional argument
+	c = TestTool{}
+	rest, err = ParseArguments([]string{"x", "serve"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"x", "serve"}, rest)
+}
+
+func TestSubcommandLoad(t *testing.T) {
+	os.Setenv("SERVE_PORT", "9090")
+	defer os.Unsetenv("SERVE_PORT")
+	os.Args = []string{"tool", "serve", "--verbose"}
+
+	c := TestTool{}
+	err := LoadE("bogus_file_name.yml", &c)
+	assert.Nil(t, err)
+	assert.Equal(t, "serve", c.Command)
+	assert.Equal(t, 9090, c.Serve.Port)
+	assert.True(t, c.Verbose)
+}
+
+func TestSubcommandUsage(t *testing.T) {
+	os.Args = []string{"tool"}
+	var b bytes.Buffer
+	assert.Nil(t, Usage(&b, &TestTool{}))
+	assert.Contains(t, b.String(), "  serve --port   ")
+	assert.Contains(t, b.String(), "  migrate --target.version   ")
+	assert.Contains(t, b.String(), "  migrate <dir>   ")
+}
+
+type TestToolChecks struct {
+	Serve struct {
+		Port int `required:"true" validate:"min=1"`
+	} `cmd:"serve"`
+	Migrate struct {
+		Version int
+	} `cmd:"migrate"`
+}
+
+// required and validate checks only cover the sections of subcommands that were chosen
+func TestSubcommandChecks(t *testing.T) {
+	os.Args = []string{"tool", "migrate", "version=7"}
+	c := TestToolChecks{}
+	p, err := Trace("bogus_file_name.yml", &c)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, c.Migrate.Version)
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "migrate", Index: 0}, p["Migrate"])
+
+	os.Args = []string{"tool", "serve"}
+	c = TestToolChecks{}
+	err = LoadE("bogus_file_name.yml", &c)
+	errs := err.(Errors)
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, "Serve.Port", errs[0].(*RequiredError).Path)
+	assert.Equal(t, "Serve.Port", errs[1].(*ValidationError).Path)
+
+	// Validate on its own doesn't know what was chosen, so it checks everything
+	assert.NotNil(t, Validate(&TestToolChecks{}))
+}
@@ -3,12 +3,11 @@ package config
 import (
 	"io/ioutil"
 	"os"
-	"strings"
+	"reflect"
+	"sort"
 
-	"github.com/creasty/defaults"
-	"github.com/mcuadros/go-lookup"
-	"github.com/vrischmann/envconfig"
 	yaml "gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
 )
 
 const (
@@ -16,89 +15,442 @@ const (
 )
 
 // Load fills in the specified struct with configuration loaded from YAML, env vars, and command line arguments.
-// It purposely ignores any errors from attempting to load from a specific source.
-func Load(file string, v interface{}) {
-	if file == "" {
-		file = defaultConfigFile
-	}
-
-	// initialize with any "default:" struct tag values
-	FromStructDefaults(v)
+// A file ending in .json, .toml, .ini, or .properties is read in that format instead of YAML.
+// Use a Loader to change which sources are used or their order.
+// It purposely ignores any errors from attempting to load from a specific source,
+// but prints Usage and exits when the command line asks for --help.
+func Load(file string, v interface{}, opts ...Option) {
+	exitForHelp(LoadE(file, v, opts...), v, opts)
+}
 
-	// overlay from local YAML config file
-	FromYamlFile(file, v)
+// LoadE fills in the specified struct exactly like Load, but reports what went wrong.
+// Every layer is still applied, and all failures are returned together as Errors,
+// each one a *SourceError naming the layer, field path, and raw input that failed,
+// a *RequiredError for a required member that no layer set,
+// or a *ValidationError for a final value that breaks its "validate:" struct tag.
+// A missing config file is not an error, but one that exists and cannot be parsed is.
+// A --help argument is reported as a *SourceError wrapping ErrHelp.
+func LoadE(file string, v interface{}, opts ...Option) error {
+	return load(file, v, nil, opts)
+}
 
-	// overlay from environment variables
-	FromEnvironment(v)
+// load applies the default layers in order, recording the origin of each member set into p when it is not nil.
+func load(file string, v interface{}, p Provenance, opts []Option) error {
+	return NewLoader(DefaultSources(file, opts...)...).apply(v, p)
+}
 
-	// overlay from command line args
-	FromArguments(os.Args[1:], v)
+// MustLoad fills in the specified struct like LoadE, and panics if any layer fails.
+// Like Load, it prints Usage and exits when the command line asks for --help.
+func MustLoad(file string, v interface{}, opts ...Option) {
+	err := LoadE(file, v, opts...)
+	exitForHelp(err, v, opts)
+	if err != nil {
+		panic(err)
+	}
 }
 
-// FromStructDefaults initializes struct members from "default:" struc tags
+// FromStructDefaults initializes struct members from "default:" struct tags.
+// Only members still holding their zero value are set, and tag values are converted like any other setting,
+// so decoders and the sep and kvsep tags apply. Afterwards the SetDefaults method of the struct,
+// and of any nested struct, is called if it has one.
+//	Level int           `default:"77"`
+//	Tags  []string      `default:"[a, b]"`
 func FromStructDefaults(v interface{}) error {
-	return defaults.Set(v)
+	return applyDefaults(v, nil)
 }
 
-// FromYaml extracts settings from a YAML string.
-func FromYaml(yml []byte, v interface{}) error {
-	return yaml.Unmarshal(yml, v)
+// defaultsSetter is implemented by structs that compute defaults the tags can't express.
+type defaultsSetter interface {
+	SetDefaults()
 }
 
-// FromYamlFile extracts settings from a YAML file.
-func FromYamlFile(path string, v interface{}) error {
-	// read YAML text file into a string
-	yml, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
+func applyDefaults(v interface{}, p Provenance) error {
+	var errs Errors
+	err := walkFields(v, func(f field) error {
+		tag, ok := f.sf.Tag.Lookup("default")
+		if !ok || !f.value.IsZero() {
+			return nil
+		}
+		if err := unmarshalField(tag, f.value, f.sf); err != nil {
+			errs.add(LayerDefaults, f.Path(), tag, err)
+			return nil
+		}
+		p.setValue(f.Path(), Origin{Layer: LayerDefaults, Name: tag}, tag, f.value.Type())
+		return nil
+	})
+	errs.add(LayerDefaults, "", "", err)
+	if err == nil {
+		callSetDefaults(reflect.ValueOf(v).Elem())
 	}
+	return errs.err()
+}
 
-	// unmarshal from string to struct
-	return FromYaml(yml, v)
+// callSetDefaults calls SetDefaults on the struct rv and its nested structs, including non-nil pointers to them, innermost first.
+func callSetDefaults(rv reflect.Value) {
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		if sv, ok := nested(rv.Field(i)); ok {
+			callSetDefaults(sv)
+		}
+	}
+	if s, ok := rv.Addr().Interface().(defaultsSetter); ok {
+		s.SetDefaults()
+	}
 }
 
-// FromEnvironment extracts settings from environment variables.
-// We expect them to be named upper case and underscore separated.
-//	SERVER_ADDRESS=http://example.com
-func FromEnvironment(v interface{}) error {
-	return envconfig.InitWithOptions(v, envconfig.Options{AllOptional: true})
+// yamlTags are the struct tags consulted for YAML key names.
+var yamlTags = []string{"yaml"}
+
+// FromYaml extracts settings from a YAML string with yaml.v2, so UnmarshalYAML methods, inline members,
+// and key names all work as yaml.v2 defines them. Members of types that yaml.v2 can't decode by itself,
+// such as url.URL, net.IPNet, complex numbers, or anything with a RegisterDecoder decoder, are then converted
+// from their text as written with UnmarshalValue, and interface{} members are typed like the other formats.
+func FromYaml(yml []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return ErrInvalidType
+	}
+	if rv.IsNil() {
+		return ErrNilPointer
+	}
+
+	// yaml.v2 decodes into a mirror of the struct that has an interface{} in place of each member
+	// decodeYamlExtras sets, so that it neither fails on them nor reports errors about them
+	typ := rv.Elem().Type()
+	mirror := yamlMirror(typ, map[reflect.Type]bool{})
+	var err error
+	if mirror == typ {
+		err = yaml.Unmarshal(yml, v)
+	} else {
+		m := reflect.New(mirror)
+		syncMirror(rv.Elem(), m.Elem(), false)
+		err = yaml.Unmarshal(yml, m.Interface())
+		syncMirror(rv.Elem(), m.Elem(), true)
+	}
+	if _, ok := err.(*yaml.TypeError); err != nil && !ok {
+		return err
+	}
+
+	tree, perr := parseYaml(yml)
+	if perr != nil {
+		return err
+	}
+	if derr := decodeYamlExtras(tree, rv, nil); derr != nil {
+		return derr
+	}
+	return err
 }
 
-// FromArguments extracts settings from a list of arguments such as those supplied on the command line.
-// All args strings must be in the form key.path=value, where key.path is a period '.' or underscore '_' separated path to the struct member.
-//	server.address=http://example.com
-func FromArguments(args []string, v interface{}) error {
-	for _, arg := range args {
-		kv := strings.Split(arg, "=")
-		if len(kv) != 2 {
+// yamlMirror returns a struct type laid out like typ for yaml.v2 to decode into, with the same names and tags,
+// in which each member that decodeYamlExtras sets is an interface{} instead, and nested structs are mirrored in turn.
+// It returns typ itself when nothing needs replacing, and for types that point back to themselves.
+func yamlMirror(typ reflect.Type, seen map[reflect.Type]bool) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		if elem := yamlMirror(typ.Elem(), seen); elem != typ.Elem() {
+			return reflect.PtrTo(elem)
+		}
+		return typ
+	}
+	if !isNestedType(typ) || yamlUnmarshals(typ) || seen[typ] {
+		return typ
+	}
+	seen[typ] = true
+	defer delete(seen, typ)
+
+	var fields []reflect.StructField
+	changed := false
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
 			continue
 		}
+		ft := sf.Type
+		switch {
+		case skipField(sf, yamlTags), yamlUnmarshals(ft):
+		case isNestedType(indirectType(ft)):
+			ft = yamlMirror(ft, seen)
+		case !yamlDecodes(ft, map[reflect.Type]bool{}):
+			ft = emptyInterfaceType
+		}
+		changed = changed || ft != sf.Type
+		fields = append(fields, reflect.StructField{Name: sf.Name, Type: ft, Tag: sf.Tag})
+	}
+	if !changed {
+		return typ
+	}
+	return reflect.StructOf(fields)
+}
 
-		// transform period separators into underscores
-		key := strings.ReplaceAll(kv[0], "_", ".")
+var emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
 
-		// find struct member matching key path
-		value, err := lookup.LookupStringI(v, key)
-		if err != nil {
-			return err
+// syncMirror copies the members of the struct orig into its yamlMirror, or back from it into orig,
+// skipping the interface{} stand-ins.
+func syncMirror(orig, mirror reflect.Value, back bool) {
+	j := 0
+	for i := 0; i < orig.NumField(); i++ {
+		if orig.Type().Field(i).PkgPath != "" {
+			continue
 		}
+		of, mf := orig.Field(i), mirror.Field(j)
+		j++
 
-		// unmarshal the string into the struct field
-		err = UnmarshalValue(kv[1], value)
-		if err != nil {
-			return err
+		src, dst := of, mf
+		if back {
+			src, dst = mf, of
+		}
+		switch {
+		case of.Type() == mf.Type():
+			dst.Set(src)
+		case mf.Kind() == reflect.Interface:
+		case of.Kind() == reflect.Ptr:
+			if src.IsNil() {
+				dst.Set(reflect.Zero(dst.Type()))
+				continue
+			}
+			if dst.IsNil() {
+				dst.Set(reflect.New(dst.Type().Elem()))
+			}
+			syncMirror(of.Elem(), mf.Elem(), back)
+		default:
+			syncMirror(of, mf, back)
 		}
 	}
+}
+
+// decodeYamlExtras walks a YAML tree alongside the struct rv, matching keys like yaml.v2,
+// and sets the members that yaml.v2 can't decode with decodeNode.
+func decodeYamlExtras(tree interface{}, rv reflect.Value, path []string) error {
+	table, ok := tree.(map[string]interface{})
+	rv = reflect.Indirect(rv)
+	if !ok || rv.Kind() != reflect.Struct {
+		return nil
+	}
 
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		index, names, ok := yamlField(rv.Type(), k)
+		if !ok {
+			continue
+		}
+		fv := rv.FieldByIndex(index)
+		fp := append(append([]string{}, path...), names...)
+
+		switch typ := fv.Type(); {
+		case yamlUnmarshals(typ):
+		case isNestedType(indirectType(typ)):
+			if err := decodeYamlExtras(table[k], fv, fp); err != nil {
+				return err
+			}
+		case !yamlDecodes(typ, map[reflect.Type]bool{}):
+			if err := decodeNode(table[k], fv, yamlTags, fp); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// yamlField finds the member of struct type typ that yaml.v2 sets from key: the one whose yaml tag name,
+// or else lower cased Go field name, is exactly key, looking inside members tagged inline.
+// It returns the member's index sequence and its Go field names.
+func yamlField(typ reflect.Type, key string) ([]int, []string, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" || skipField(sf, yamlTags) {
+			continue
+		}
+		if hasTagOption(sf, yamlTags, "inline") {
+			if sf.Type.Kind() != reflect.Struct {
+				continue
+			}
+			if index, names, ok := yamlField(sf.Type, key); ok {
+				return append([]int{i}, index...), append([]string{sf.Name}, names...), true
+			}
+			continue
+		}
+		if keyName(sf, yamlTags) == key {
+			return []int{i}, []string{sf.Name}, true
+		}
+	}
+	return nil, nil, false
+}
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// yamlUnmarshals reports whether yaml.v2 decodes values of typ through its own UnmarshalYAML or UnmarshalText method.
+func yamlUnmarshals(typ reflect.Type) bool {
+	typ = indirectType(typ)
+	ptr := reflect.PtrTo(typ)
+	return ptr.Implements(yamlUnmarshalerType) || ptr.Implements(textUnmarshalerType)
+}
+
+// yamlDecodes reports whether yaml.v2 can decode values of typ by itself. It can't decode types that only
+// a RegisterDecoder decoder or flag.Value understands, complex numbers, or anything holding them,
+// and interface{} is left to decodeNode so it is typed the same way as in the other formats.
+func yamlDecodes(typ reflect.Type, seen map[reflect.Type]bool) bool {
+	if yamlUnmarshals(typ) || seen[typ] {
+		return true
+	}
+	seen[typ] = true
+	if hasDecoder(typ) {
+		return false
+	}
+
+	switch typ.Kind() {
+	case reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Interface:
+		return typ.NumMethod() != 0
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return yamlDecodes(typ.Elem(), seen)
+	case reflect.Map:
+		return yamlDecodes(typ.Key(), seen) && yamlDecodes(typ.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if sf := typ.Field(i); sf.PkgPath == "" && !yamlDecodes(sf.Type, seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseYaml decodes a YAML document into a tree whose scalars are the text of the document,
+// with aliases followed and << merge keys applied.
+func parseYaml(yml []byte) (interface{}, error) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(yml, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return yamlNode(doc.Content[0]), nil
+}
+
+func yamlNode(node *yaml3.Node) interface{} {
+	switch node.Kind {
+	case yaml3.AliasNode:
+		return yamlNode(node.Alias)
+
+	case yaml3.MappingNode:
+		m := map[string]interface{}{}
+		var merges []*yaml3.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Tag == "!!merge" {
+				merges = append(merges, value)
+				continue
+			}
+			m[key.Value] = yamlNode(value)
+		}
+		// keys written in the mapping itself win over merged ones
+		for _, merge := range merges {
+			sources := []*yaml3.Node{merge}
+			if merge.Kind == yaml3.SequenceNode {
+				sources = merge.Content
+			}
+			for _, src := range sources {
+				merged, _ := yamlNode(src).(map[string]interface{})
+				for k, v := range merged {
+					if _, ok := m[k]; !ok {
+						m[k] = v
+					}
+				}
+			}
+		}
+		return m
+
+	case yaml3.SequenceNode:
+		list := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			list[i] = yamlNode(item)
+		}
+		return list
+	}
+
+	switch node.Tag {
+	case "!!null":
+		return nil
+	case "!!str":
+		return node.Value
+	}
+	return plainText(node.Value)
+}
+
+// plainText is a scalar the document leaves untyped, such as an unquoted 10 in YAML,
+// so an interface{} member infers its type from the text. Quoted strings stay plain strings.
+type plainText string
+
+// FromYamlFile extracts settings from a YAML file.
+func FromYamlFile(path string, v interface{}) error {
+	return applyYamlFile(path, v, nil)
+}
+
+func applyYamlFile(path string, v interface{}, p Provenance) error {
+	// read YAML text file into a string
+	yml, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// unmarshal from string to struct
+	err = FromYaml(yml, v)
+	if err == nil && p != nil {
+		traceYaml(yml, path, v, p)
+	}
+	return err
+}
+
+// FromEnvironment extracts settings from environment variables.
+// We expect them to be named upper case and underscore separated, after any WithEnvPrefix option.
+// A member tagged `env:"NAME"` is read from exactly NAME instead.
+//	SERVER_ADDRESS=http://example.com
+func FromEnvironment(v interface{}, opts ...Option) error {
+	return applyEnvironment(v, newOptions(opts).envPrefix, nil)
+}
+
+func applyEnvironment(v interface{}, prefix string, p Provenance) error {
+	return applyVars(v, LayerEnvironment, prefix, func(name string) (string, Origin, bool) {
+		value, ok := os.LookupEnv(name)
+		return value, Origin{Layer: LayerEnvironment, Name: name}, ok
+	}, p)
+}
+
 // ToYaml marshals the struc into a YAML string.
+// Members tagged `secret:"true"` or of type Secret are written as ******.
+// Values are written as text that FromYaml reads back unchanged, such as 0644 for an os.FileMode.
 func ToYaml(v interface{}) (string, error) {
-	buff, err := yaml.Marshal(v)
+	buff, err := yaml.Marshal(yamlTree(encodeTree(reflect.ValueOf(Redacted(v)), yamlTags)))
 	if err == nil {
 		return "---\n" + string(buff), nil
 	}
 
 	return "", err
 }
+
+// yamlTree converts the tables of an encoded tree into ordered YAML mappings.
+func yamlTree(node interface{}) interface{} {
+	switch n := node.(type) {
+	case treeMap:
+		m := make(yaml.MapSlice, len(n))
+		for i, item := range n {
+			m[i] = yaml.MapItem{Key: item.Key, Value: yamlTree(item.Value)}
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(n))
+		for i, item := range n {
+			list[i] = yamlTree(item)
+		}
+		return list
+	}
+	return node
+}
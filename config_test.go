@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -137,6 +139,42 @@ func TestFromStructDefaultsNested(t *testing.T) {
 	assert.Equal(t, 77, cfg.Sub.Level)
 }
 
+type TestDefaultsCollections struct {
+	Tags   []string       `default:"[a, b]"`
+	Limits map[string]int `default:"{x: 1, y: 2}"`
+	Hosts  []string       `default:"c;d" sep:";"`
+	Name   string
+}
+
+func (d *TestDefaultsCollections) SetDefaults() {
+	if d.Name == "" {
+		d.Name = strings.Join(d.Tags, "-")
+	}
+}
+
+// collections use the same syntax as other sources, and SetDefaults runs after the tags
+func TestFromStructDefaultsCollections(t *testing.T) {
+	cfg := TestDefaultsCollections{}
+	err := FromStructDefaults(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, cfg.Tags)
+	assert.Equal(t, map[string]int{"x": 1, "y": 2}, cfg.Limits)
+	assert.Equal(t, []string{"c", "d"}, cfg.Hosts)
+	assert.Equal(t, "a-b", cfg.Name)
+}
+
+// a bad default is reported as a defaults layer *SourceError
+func TestFromStructDefaultsBad(t *testing.T) {
+	type bad struct {
+		Count int `default:"many"`
+	}
+	err := FromStructDefaults(&bad{})
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	assert.Equal(t, LayerDefaults, errs[0].(*SourceError).Layer)
+	assert.Equal(t, "Count", errs[0].(*SourceError).Path)
+}
+
 // parsing a good YAML string succeeds
 func TestFromYaml(t *testing.T) {
 	cfg := TestYaml{}
@@ -149,6 +187,60 @@ func TestFromYaml(t *testing.T) {
 	assert.Equal(t, (2*time.Minute)+(22*time.Second), cfg.Period)
 }
 
+// yamlPriority decodes itself with UnmarshalYAML, which FromYaml must honor
+type yamlPriority int
+
+func (p *yamlPriority) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	switch s {
+	case "low":
+		*p = 1
+	case "high":
+		*p = 9
+	default:
+		return fmt.Errorf("unknown priority %q", s)
+	}
+	return nil
+}
+
+func TestFromYamlUnmarshaler(t *testing.T) {
+	cfg := struct {
+		Priority yamlPriority
+		Queue    struct {
+			Priority yamlPriority
+		}
+	}{}
+	err := FromYaml([]byte("priority: high\nqueue:\n  priority: low\n"), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, yamlPriority(9), cfg.Priority)
+	assert.Equal(t, yamlPriority(1), cfg.Queue.Priority)
+}
+
+type yamlBase struct {
+	Host string
+	Port int
+}
+
+// inline members take their keys from the parent mapping, and keys match exactly as in yaml.v2
+func TestFromYamlInline(t *testing.T) {
+	cfg := struct {
+		Base yamlBase `yaml:",inline"`
+		Name string
+	}{}
+	err := FromYaml([]byte("host: h\nport: 80\nNAME: ignored\n"), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "h", cfg.Base.Host)
+	assert.Equal(t, 80, cfg.Base.Port)
+	assert.Equal(t, "", cfg.Name)
+
+	s, err := ToYaml(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "---\nhost: h\nport: 80\nname: \"\"\n", s)
+}
+
 // parsing a bad YAML string fails
 func TestFromYamlBad(t *testing.T) {
 	cfg := TestYaml{}
@@ -264,6 +356,104 @@ func TestFromEnvironmentDefault(t *testing.T) {
 	assert.Equal(t, time.Duration(0), cfg.Period)
 }
 
+type TestEnvironmentPrefix struct {
+	Address string
+	Sub     sub
+	Token   string `env:"API_TOKEN"`
+}
+
+// a prefix applies to every generated name, but not to env tag overrides
+func TestFromEnvironmentPrefix(t *testing.T) {
+	os.Setenv("MYAPP_ADDRESS", "http://example.com/prefixed")
+	os.Setenv("MYAPP_SUB_LEVEL", "9")
+	os.Setenv("API_TOKEN", "abc")
+	os.Setenv("ADDRESS", "http://example.com/unprefixed")
+	defer os.Unsetenv("MYAPP_ADDRESS")
+	defer os.Unsetenv("MYAPP_SUB_LEVEL")
+	defer os.Unsetenv("API_TOKEN")
+	defer os.Unsetenv("ADDRESS")
+
+	cfg := TestEnvironmentPrefix{}
+	err := FromEnvironment(&cfg, WithEnvPrefix("myapp"))
+	assert.Nil(t, err)
+	assert.Equal(t, "http://example.com/prefixed", cfg.Address)
+	assert.Equal(t, 9, cfg.Sub.Level)
+	assert.Equal(t, "abc", cfg.Token)
+
+	cfg = TestEnvironmentPrefix{}
+	err = FromEnvironment(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://example.com/unprefixed", cfg.Address)
+	assert.Equal(t, 0, cfg.Sub.Level)
+	assert.Equal(t, "abc", cfg.Token)
+}
+
+type TestEnvironmentPointer struct {
+	Sub   *SubNested
+	Other *SubNested
+	Node  *envNode
+}
+
+type envNode struct {
+	Name string
+	Next *envNode
+}
+
+// a nil nested struct is allocated when a variable for one of its members is present, and left nil otherwise.
+// A struct that points to its own type is only followed one level, rather than forever.
+func TestFromEnvironmentPointer(t *testing.T) {
+	os.Setenv("SUB_LEVEL", "9")
+	os.Setenv("NODE_NAME", "a")
+	defer os.Unsetenv("SUB_LEVEL")
+	defer os.Unsetenv("NODE_NAME")
+
+	os.Args = []string{"test"}
+	cfg := TestEnvironmentPointer{}
+	p, err := Trace("bogus_file_name.yml", &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, &SubNested{Level: 9}, cfg.Sub)
+	assert.Nil(t, cfg.Other)
+	assert.Equal(t, &envNode{Name: "a"}, cfg.Node)
+	assert.Equal(t, Origin{Layer: LayerEnvironment, Name: "SUB_LEVEL"}, p["Sub.Level"])
+}
+
+type TestEnvironmentCollections struct {
+	Sub struct {
+		Tags   []string
+		Limits map[string]int
+	}
+}
+
+// environment variables and command line arguments convert values the same way
+func TestFromEnvironmentMatchesArguments(t *testing.T) {
+	os.Setenv("SUB_TAGS", "a,b")
+	os.Setenv("SUB_LIMITS", "x:1,y:2")
+	defer os.Unsetenv("SUB_TAGS")
+	defer os.Unsetenv("SUB_LIMITS")
+
+	env := TestEnvironmentCollections{}
+	assert.Nil(t, FromEnvironment(&env))
+	args := TestEnvironmentCollections{}
+	assert.Nil(t, FromArguments([]string{"sub.tags=a,b", "sub.limits=x:1,y:2"}, &args))
+	assert.Equal(t, args, env)
+	assert.Equal(t, []string{"a", "b"}, env.Sub.Tags)
+}
+
+// a bad environment variable is reported as a *SourceError naming the variable
+func TestFromEnvironmentInvalidValue(t *testing.T) {
+	os.Setenv("COUNT", "many")
+	defer os.Unsetenv("COUNT")
+
+	cfg := TestEnvironment{}
+	err := FromEnvironment(&cfg)
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	se := errs[0].(*SourceError)
+	assert.Equal(t, LayerEnvironment, se.Layer)
+	assert.Equal(t, "Count", se.Path)
+	assert.Equal(t, "COUNT=many", se.Input)
+}
+
 // parse from command line arguments into struct members
 func TestFromArguments(t *testing.T) {
 	os.Args = []string{
@@ -326,3 +516,65 @@ func TestFromArgumentsInvalidValue(t *testing.T) {
 	assert.Equal(t, false, c.Sub.Enabled)
 	assert.Equal(t, 0, c.Sub.Level)
 }
+
+// a missing config file is acceptable, and the other layers are still applied
+func TestLoadEMissingFile(t *testing.T) {
+	os.Args = []string{"test", "Sub.Level=42"}
+
+	cfg := TestDefaultsNested{}
+	err := LoadE("bogus_file_name.yml", &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://dosan.com/", cfg.Address)
+	assert.Equal(t, 42, cfg.Sub.Level)
+}
+
+// an existing but unparsable config file is reported as a file layer error
+func TestLoadEBadFile(t *testing.T) {
+	file, err := ioutil.TempFile(".", "yaml_test_bad")
+	assert.Nil(t, err, "Got error trying to create temporary YAML file")
+	defer os.Remove(file.Name())
+	file.Write([]byte(notYml))
+	file.Close()
+
+	os.Args = []string{"test"}
+	cfg := TestYaml{}
+	err = LoadE(file.Name(), &cfg)
+	assert.NotNil(t, err)
+
+	errs, ok := err.(Errors)
+	assert.True(t, ok, "Expected Errors")
+	assert.Equal(t, 1, len(errs))
+	serr, ok := errs[0].(*SourceError)
+	assert.True(t, ok, "Expected *SourceError")
+	assert.Equal(t, LayerFile, serr.Layer)
+	assert.Equal(t, file.Name(), serr.Input)
+}
+
+// every bad argument is reported, with its field path and raw input
+func TestLoadEBadArguments(t *testing.T) {
+	os.Args = []string{"test", "Timeout=abc", "Sub.Level=high", "Address=http://example.com"}
+
+	cfg := TestArguments{}
+	err := LoadE("bogus_file_name.yml", &cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, "http://example.com", cfg.Address)
+
+	errs, ok := err.(Errors)
+	assert.True(t, ok, "Expected Errors")
+	assert.Equal(t, 2, len(errs))
+	serr := errs[0].(*SourceError)
+	assert.Equal(t, LayerArguments, serr.Layer)
+	assert.Equal(t, "Timeout", serr.Path)
+	assert.Equal(t, "Timeout=abc", serr.Input)
+	serr = errs[1].(*SourceError)
+	assert.Equal(t, "Sub.Level", serr.Path)
+	assert.Equal(t, "Sub.Level=high", serr.Input)
+}
+
+// MustLoad panics when any layer fails
+func TestMustLoadPanics(t *testing.T) {
+	os.Args = []string{"test", "Timeout=abc"}
+
+	cfg := TestArguments{}
+	assert.Panics(t, func() { MustLoad("bogus_file_name.yml", &cfg) })
+}
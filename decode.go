@@ -0,0 +1,79 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"reflect"
+	"sync"
+)
+
+// ErrDecoderType indicates a registered decoder that returned a value of the wrong type
+var ErrDecoderType = errors.New("decoder returned a value of the wrong type")
+
+// DecoderFunc converts the text of a setting into a value of the type it was registered for.
+type DecoderFunc func(s string) (interface{}, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[reflect.Type]DecoderFunc{}
+)
+
+// RegisterDecoder makes UnmarshalValue convert text into typ by calling fn, replacing any earlier decoder for typ.
+// Registering a pointer type such as *url.URL also covers nil pointer members of that type.
+// Registered decoders take precedence over encoding.TextUnmarshaler and flag.Value, which are used next,
+// and then over the built in conversions.
+//	config.RegisterDecoder(reflect.TypeOf(logLevel(0)), func(s string) (interface{}, error) {
+//		return parseLogLevel(s)
+//	})
+func RegisterDecoder(typ reflect.Type, fn DecoderFunc) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[typ] = fn
+}
+
+func decoder(typ reflect.Type) (DecoderFunc, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	fn, ok := decoders[typ]
+	return fn, ok
+}
+
+// hasDecoder reports whether values of typ are converted as a whole rather than member by member or by kind.
+func hasDecoder(typ reflect.Type) bool {
+	if _, ok := decoder(typ); ok {
+		return true
+	}
+	ptr := reflect.PtrTo(typ)
+	return ptr.Implements(textUnmarshalerType) || ptr.Implements(flagValueType)
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+)
+
+// decode converts s into the settable rv with a registered decoder, UnmarshalText, or Set,
+// reporting whether any of them applies to its type.
+func decode(s string, rv reflect.Value) (bool, error) {
+	if fn, ok := decoder(rv.Type()); ok {
+		v, err := fn(s)
+		if err != nil {
+			return true, err
+		}
+		dv := reflect.ValueOf(v)
+		if !dv.IsValid() || !dv.Type().AssignableTo(rv.Type()) {
+			return true, ErrDecoderType
+		}
+		rv.Set(dv)
+		return true, nil
+	}
+
+	switch u := rv.Addr().Interface().(type) {
+	case encoding.TextUnmarshaler:
+		return true, u.UnmarshalText([]byte(s))
+	case flag.Value:
+		return true, u.Set(s)
+	}
+	return false, nil
+}
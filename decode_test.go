@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// testLevel is an enum set through a registered decoder
+type testLevel int
+
+// testMode is an enum set through flag.Value
+type testMode string
+
+func (m *testMode) String() string { return string(*m) }
+
+func (m *testMode) Set(s string) error {
+	switch s {
+	case "fast", "safe":
+		*m = testMode(s)
+		return nil
+	}
+	return errors.New("mode must be fast or safe")
+}
+
+// testPoint is a struct decoded as a single value through encoding.TextUnmarshaler
+type testPoint struct {
+	X, Y string
+}
+
+func (p *testPoint) UnmarshalText(text []byte) error {
+	parts := strings.Split(string(text), "x")
+	if len(parts) != 2 {
+		return errors.New("point must be XxY")
+	}
+	p.X, p.Y = parts[0], parts[1]
+	return nil
+}
+
+type TestDecoders struct {
+	IP     net.IP    `default:"127.0.0.1"`
+	Level  testLevel `default:"info"`
+	Mode   testMode
+	Origin testPoint
+	Site   *url.URL
+	Ranks  []testLevel
+}
+
+func registerTestLevel(t *testing.T) {
+	RegisterDecoder(reflect.TypeOf(testLevel(0)), func(s string) (interface{}, error) {
+		for i, name := range []string{"debug", "info", "warn"} {
+			if s == name {
+				return testLevel(i), nil
+			}
+		}
+		return nil, errors.New("unknown level")
+	})
+	t.Cleanup(func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+		delete(decoders, reflect.TypeOf(testLevel(0)))
+	})
+}
+
+func TestUnmarshalDecoders(t *testing.T) {
+	registerTestLevel(t)
+
+	var ip net.IP
+	assert.Nil(t, Unmarshal("10.0.0.1", &ip))
+	assert.Equal(t, net.ParseIP("10.0.0.1"), ip)
+
+	var mode testMode
+	assert.Nil(t, Unmarshal("safe", &mode))
+	assert.Equal(t, testMode("safe"), mode)
+	assert.NotNil(t, Unmarshal("slow", &mode))
+
+	var levels []testLevel
+	assert.Nil(t, Unmarshal("warn, debug", &levels))
+	assert.Equal(t, []testLevel{2, 0}, levels)
+
+	var site *url.URL
+	assert.Nil(t, Unmarshal("http://example.com/x", &site))
+	assert.Equal(t, "example.com", site.Host)
+}
+
+func TestDecodersInEverySource(t *testing.T) {
+	registerTestLevel(t)
+	os.Setenv("ORIGIN", "3x4")
+	os.Setenv("MODE", "fast")
+	defer os.Unsetenv("ORIGIN")
+	defer os.Unsetenv("MODE")
+
+	cfg := TestDecoders{}
+	err := NewLoader(StructDefaults(), Environment(), Arguments([]string{"--site", "https://example.com", "ranks=warn"})).Load(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, net.ParseIP("127.0.0.1"), cfg.IP)
+	assert.Equal(t, testLevel(1), cfg.Level)
+	assert.Equal(t, testMode("fast"), cfg.Mode)
+	assert.Equal(t, testPoint{"3", "4"}, cfg.Origin)
+	assert.Equal(t, "example.com", cfg.Site.Host)
+	assert.Equal(t, []testLevel{2}, cfg.Ranks)
+}
+
+// yaml.v2 decodes what it can, and the rest goes through the decoders
+func TestDecodersInYaml(t *testing.T) {
+	registerTestLevel(t)
+	yml := `
+level: warn
+mode: safe
+site: https://example.com/x
+ranks: [debug, info]
+ip: 10.0.0.1
+`
+	cfg := TestDecoders{}
+	err := FromYaml([]byte(yml), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, testLevel(2), cfg.Level)
+	assert.Equal(t, testMode("safe"), cfg.Mode)
+	assert.Equal(t, "example.com", cfg.Site.Host)
+	assert.Equal(t, []testLevel{0, 1}, cfg.Ranks)
+	assert.Equal(t, net.ParseIP("10.0.0.1"), cfg.IP)
+
+	err = FromYaml([]byte("level: loud\n"), &cfg)
+	assert.Equal(t, "Level", err.(*SourceError).Path)
+
+	// yaml.v2 still reports the members it couldn't decode
+	other := struct {
+		Level testLevel
+		Count int
+	}{}
+	err = FromYaml([]byte("level: warn\ncount: many\n"), &other)
+	assert.Equal(t, testLevel(2), other.Level)
+	assert.Equal(t, &yaml.TypeError{Errors: []string{"line 2: cannot unmarshal !!str `many` into int"}}, err)
+
+	// errors about other members are kept even when they share a type with the members decoded here,
+	// including inside nested structs
+	nested := struct {
+		Names map[string]testLevel
+		Name  string
+		Sub   *struct {
+			Level testLevel
+			Count int
+		}
+	}{}
+	err = FromYaml([]byte("names: {a: warn}\nname: {x: y}\nsub:\n  level: info\n  count: lots\n"), &nested)
+	assert.Equal(t, map[string]testLevel{"a": 2}, nested.Names)
+	assert.Equal(t, testLevel(1), nested.Sub.Level)
+	assert.Equal(t, &yaml.TypeError{Errors: []string{
+		"line 2: cannot unmarshal !!map into string",
+		"line 5: cannot unmarshal !!str `lots` into int",
+	}}, err)
+}
+
+func TestDecoderWrongType(t *testing.T) {
+	typ := reflect.TypeOf(testLevel(0))
+	RegisterDecoder(typ, func(s string) (interface{}, error) { return s, nil })
+	defer func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+		delete(decoders, typ)
+	}()
+
+	var level testLevel
+	assert.Equal(t, ErrDecoderType, Unmarshal("info", &level))
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Change describes one struct member whose value differs between two configurations.
+type Change struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// String formats the change for audit logs.
+//	Sub.Level: 42 -> 7
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Diff compares two configuration structs member by member, using the same "Sub.Level" style paths as Trace,
+// and returns every member whose value differs, in struct order. Members of nested structs reached
+// through non-nil pointers are compared too.
+// A member present in only one of them is reported with a nil value on the other side.
+func Diff(before, after interface{}) []Change {
+	oldValues := snapshot(before)
+	newValues := snapshot(after)
+
+	var changes []Change
+	compare := func(f field) error {
+		path := f.Path()
+		o, inOld := oldValues[path]
+		n, inNew := newValues[path]
+		if inOld != inNew || !reflect.DeepEqual(o, n) {
+			changes = append(changes, Change{Path: path, Old: o, New: n})
+		}
+		delete(oldValues, path)
+		delete(newValues, path)
+		return nil
+	}
+	walkFields(after, compare)
+	walkFields(before, compare)
+
+	return changes
+}
+
+// Changed reports whether any of the changes is to the member at path, or to a member nested beneath it,
+// so that a subsystem can tell whether it must restart.
+//	if config.Changed(changes, "HTTP") { restartServer() }
+func Changed(changes []Change, path string) bool {
+	for _, c := range changes {
+		if strings.EqualFold(c.Path, path) || strings.HasPrefix(strings.ToLower(c.Path), strings.ToLower(path)+".") {
+			return true
+		}
+	}
+	return false
+}
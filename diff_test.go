@@ -0,0 +1,75 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := TestArguments{Address: "http://a.com", Timeout: time.Minute, Sub: sub{Enabled: true, Level: 42}}
+	after := before
+	after.Timeout = 2 * time.Minute
+	after.Sub.Level = 7
+
+	changes := Diff(&before, &after)
+	assert.Equal(t, []Change{
+		{Path: "Timeout", Old: time.Minute, New: 2 * time.Minute},
+		{Path: "Sub.Level", Old: 42, New: 7},
+	}, changes)
+	assert.Equal(t, "Sub.Level: 42 -> 7", changes[1].String())
+}
+
+func TestDiffEqual(t *testing.T) {
+	before := TestCollections{Tags: []string{"a"}, Limits: map[string]int{"cpu": 1}}
+	after := TestCollections{Tags: []string{"a"}, Limits: map[string]int{"cpu": 1}}
+	assert.Nil(t, Diff(before, &after))
+
+	after.Limits["cpu"] = 2
+	assert.Equal(t, []Change{{Path: "Limits", Old: map[string]int{"cpu": 1}, New: map[string]int{"cpu": 2}}}, Diff(before, after))
+}
+
+// members that exist on only one side are reported against nil
+func TestDiffDifferentTypes(t *testing.T) {
+	before := TestYaml{Address: "x", Count: 1}
+	after := TestArguments{Address: "x"}
+
+	changes := Diff(&before, &after)
+	assert.Equal(t, []Change{
+		{Path: "Timeout", Old: nil, New: time.Duration(0)},
+		{Path: "Sub.Enabled", Old: nil, New: false},
+		{Path: "Sub.Level", Old: nil, New: 0},
+		{Path: "Count", Old: 1, New: nil},
+		{Path: "Passive", Old: false, New: nil},
+		{Path: "Period", Old: time.Duration(0), New: nil},
+	}, changes)
+}
+
+func TestChanged(t *testing.T) {
+	changes := []Change{{Path: "HTTP.Timeout"}, {Path: "Level"}}
+	assert.True(t, Changed(changes, "HTTP"))
+	assert.True(t, Changed(changes, "http.timeout"))
+	assert.True(t, Changed(changes, "Level"))
+	assert.False(t, Changed(changes, "HTTP.Address"))
+	assert.False(t, Changed(changes, "Lev"))
+	assert.False(t, Changed(nil, "Level"))
+}
+
+type TestCollections struct {
+	Tags   []string
+	Limits map[string]int
+}
+
+// members of pointed to nested structs are compared, not the pointers
+func TestDiffPointer(t *testing.T) {
+	type config struct {
+		Sub *sub
+	}
+	before := config{Sub: &sub{Level: 42}}
+	after := config{Sub: &sub{Level: 7}}
+	assert.Equal(t, []Change{{Path: "Sub.Level", Old: 42, New: 7}}, Diff(&before, &after))
+
+	after.Sub.Level = 42
+	assert.Nil(t, Diff(&before, &after))
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// LayerDotEnv is a .env file of environment variable assignments
+const LayerDotEnv Layer = "dotenv"
+
+var (
+	// ErrBadDotEnvLine indicates a .env line that is not a KEY=VALUE assignment
+	ErrBadDotEnvLine = errors.New("dotenv line must be KEY=VALUE")
+	// ErrUnterminatedQuote indicates a .env value with an opening quote but no closing one
+	ErrUnterminatedQuote = errors.New("dotenv value is missing its closing quote")
+)
+
+// dotEnvVar is one assignment from a .env file.
+type dotEnvVar struct {
+	value string
+	line  int
+}
+
+// FromDotEnv extracts settings from the contents of a .env file.
+// Variables are matched to struct members using the same names as FromEnvironment, including any WithEnvPrefix option.
+// The process environment is only read, to interpolate ${VAR} references, and is never modified.
+//	# comments and blank lines are ignored
+//	export SERVER_ADDRESS=http://example.com
+//	SERVER_TIMEOUT="1m"   # double quotes allow \n escapes and ${VAR} interpolation
+//	SERVER_NAME='$literal'
+func FromDotEnv(env []byte, v interface{}, opts ...Option) error {
+	return applyDotEnv(env, "", v, newOptions(opts).envPrefix, nil)
+}
+
+// FromDotEnvFile extracts settings from a .env file.
+func FromDotEnvFile(path string, v interface{}, opts ...Option) error {
+	return applyDotEnvFile(path, v, newOptions(opts).envPrefix, nil)
+}
+
+func applyDotEnvFile(path string, v interface{}, prefix string, p Provenance) error {
+	env, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return applyDotEnv(env, path, v, prefix, p)
+}
+
+func applyDotEnv(env []byte, path string, v interface{}, prefix string, p Provenance) error {
+	vars, err := parseDotEnv(env)
+	if err != nil {
+		return err
+	}
+
+	return applyVars(v, LayerDotEnv, prefix, func(name string) (string, Origin, bool) {
+		dv, ok := vars[name]
+		return dv.value, Origin{Layer: LayerDotEnv, Name: path, Line: dv.line}, ok
+	}, p)
+}
+
+// parseDotEnv reads every assignment in a .env file, interpolating references to earlier
+// assignments or to the process environment.
+func parseDotEnv(env []byte) (map[string]dotEnvVar, error) {
+	vars := map[string]dotEnvVar{}
+	lookup := func(name string) string {
+		if dv, ok := vars[name]; ok {
+			return dv.value
+		}
+		return os.Getenv(name)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(env))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		eq := strings.Index(text, "=")
+		if eq < 1 {
+			return nil, &SourceError{Layer: LayerDotEnv, Input: scanner.Text(), Err: ErrBadDotEnvLine}
+		}
+		key := strings.TrimSpace(text[:eq])
+		if strings.ContainsAny(key, " \t") {
+			return nil, &SourceError{Layer: LayerDotEnv, Input: scanner.Text(), Err: ErrBadDotEnvLine}
+		}
+
+		value, err := parseDotEnvValue(strings.TrimSpace(text[eq+1:]), lookup)
+		if err != nil {
+			return nil, &SourceError{Layer: LayerDotEnv, Path: key, Input: scanner.Text(), Err: err}
+		}
+		vars[key] = dotEnvVar{value: value, line: line}
+	}
+
+	return vars, scanner.Err()
+}
+
+// parseDotEnvValue unquotes a .env value and expands its variable references.
+// Single quoted values are literal, double quoted values honor backslash escapes,
+// and unquoted values end at a " #" comment.
+func parseDotEnvValue(s string, lookup func(string) string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	switch s[0] {
+	case '\'':
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", ErrUnterminatedQuote
+		}
+		return s[1 : end+1], trailingComment(s[end+2:])
+
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '"':
+				return b.String(), trailingComment(s[i+1:])
+			case c == '\\' && i+1 < len(s):
+				i++
+				switch s[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case 'r':
+					b.WriteByte('\r')
+				default:
+					b.WriteByte(s[i])
+				}
+			case c == '$':
+				name, n := varReference(s[i:])
+				if n == 0 {
+					b.WriteByte(c)
+					continue
+				}
+				b.WriteString(lookup(name))
+				i += n - 1
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", ErrUnterminatedQuote
+	}
+
+	if hash := strings.Index(s, " #"); hash >= 0 {
+		s = strings.TrimSpace(s[:hash])
+	}
+	return os.Expand(s, lookup), nil
+}
+
+// varReference parses a $NAME or ${NAME} reference at the start of s, returning the name and its length, or 0 if there is none.
+func varReference(s string) (string, int) {
+	if strings.HasPrefix(s, "${") {
+		end := strings.Index(s, "}")
+		if end < 0 {
+			return "", 0
+		}
+		return s[2:end], end + 1
+	}
+
+	n := 1
+	for n < len(s) && (s[n] == '_' || s[n] >= 'A' && s[n] <= 'Z' || s[n] >= 'a' && s[n] <= 'z' || n > 1 && s[n] >= '0' && s[n] <= '9') {
+		n++
+	}
+	if n == 1 {
+		return "", 0
+	}
+	return s[1:n], n
+}
+
+// trailingComment checks that only whitespace or a comment follows a closing quote.
+func trailingComment(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest == "" || strings.HasPrefix(rest, "#") {
+		return nil
+	}
+	return ErrBadDotEnvLine
+}
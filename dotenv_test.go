@@ -0,0 +1,107 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var dotEnv = `# service settings
+export ADDRESS=http://example.com/ # trailing comment
+TIMEOUT="1m30s"
+
+SUB_ENABLED='true'
+SUB_LEVEL=${DOTENV_TEST_LEVEL}
+`
+
+// parse a .env string into config struct members, without touching the environment
+func TestFromDotEnv(t *testing.T) {
+	os.Setenv("DOTENV_TEST_LEVEL", "42")
+	defer os.Unsetenv("DOTENV_TEST_LEVEL")
+
+	cfg := TestArguments{}
+	err := FromDotEnv([]byte(dotEnv), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://example.com/", cfg.Address)
+	assert.Equal(t, (time.Minute)+(30*time.Second), cfg.Timeout)
+	assert.Equal(t, true, cfg.Sub.Enabled)
+	assert.Equal(t, 42, cfg.Sub.Level)
+
+	_, ok := os.LookupEnv("ADDRESS")
+	assert.False(t, ok, "Expected the environment to be left alone")
+}
+
+func TestParseDotEnv(t *testing.T) {
+	env := `A=plain
+B = "quoted # not a comment"
+C="line\none \"q\" \$A"
+D='single $A \n'
+E="${A}-$A"
+F=$A/$B
+G=
+`
+	vars, err := parseDotEnv([]byte(env))
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", vars["A"].value)
+	assert.Equal(t, 1, vars["A"].line)
+	assert.Equal(t, "quoted # not a comment", vars["B"].value)
+	assert.Equal(t, "line\none \"q\" $A", vars["C"].value)
+	assert.Equal(t, `single $A \n`, vars["D"].value)
+	assert.Equal(t, "plain-plain", vars["E"].value)
+	assert.Equal(t, "plain/quoted # not a comment", vars["F"].value)
+	assert.Equal(t, "", vars["G"].value)
+	assert.Equal(t, 7, vars["G"].line)
+}
+
+func TestParseDotEnvBad(t *testing.T) {
+	_, err := parseDotEnv([]byte("JUST_A_NAME\n"))
+	assert.Equal(t, ErrBadDotEnvLine, err.(*SourceError).Err)
+
+	_, err = parseDotEnv([]byte("BAD KEY=1\n"))
+	assert.Equal(t, ErrBadDotEnvLine, err.(*SourceError).Err)
+
+	_, err = parseDotEnv([]byte(`A="open`))
+	assert.Equal(t, ErrUnterminatedQuote, err.(*SourceError).Err)
+
+	_, err = parseDotEnv([]byte(`A='x' y`))
+	assert.Equal(t, ErrBadDotEnvLine, err.(*SourceError).Err)
+}
+
+// a value that doesn't convert reports the member path and variable
+func TestFromDotEnvBadValue(t *testing.T) {
+	cfg := TestArguments{}
+	err := FromDotEnv([]byte("SUB_LEVEL=high\n"), &cfg)
+	errs := err.(Errors)
+	assert.Equal(t, 1, len(errs))
+	serr := errs[0].(*SourceError)
+	assert.Equal(t, LayerDotEnv, serr.Layer)
+	assert.Equal(t, "Sub.Level", serr.Path)
+	assert.Equal(t, "SUB_LEVEL=high", serr.Input)
+}
+
+// Load applies a .env file after the config file and before the real environment
+func TestLoadWithDotEnv(t *testing.T) {
+	file, err := ioutil.TempFile(".", "dotenv_test")
+	assert.Nil(t, err, "Got error trying to create temporary .env file")
+	defer os.Remove(file.Name())
+	file.Write([]byte("ADDRESS=http://dotenv.com/\nSUB_LEVEL=5\n"))
+	file.Close()
+
+	os.Setenv("SUB_LEVEL", "6")
+	defer os.Unsetenv("SUB_LEVEL")
+	os.Args = []string{"test"}
+
+	cfg := TestDefaultsNested{}
+	p, err := Trace("bogus_file_name.yml", &cfg, WithDotEnv(file.Name()))
+	assert.Nil(t, err)
+	assert.Equal(t, "http://dotenv.com/", cfg.Address)
+	assert.Equal(t, 6, cfg.Sub.Level)
+	assert.Equal(t, Origin{Layer: LayerDotEnv, Name: file.Name(), Line: 1}, p["Address"])
+
+	// a missing .env file is skipped
+	err = LoadE("bogus_file_name.yml", &cfg, WithDotEnv("bogus.env"))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// envName returns the environment variable name for a struct member path.
+// Path parts are upper cased and joined by underscores, and camel case words are split apart.
+//	Sub.LogLevel -> SUB_LOG_LEVEL
+func envName(path []string) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = strings.Join(splitWords(p), "_")
+	}
+	return strings.ToUpper(strings.Join(parts, "_"))
+}
+
+// envNames returns every environment variable name accepted for a struct member path,
+// the preferred envName first, followed by the form without camel case splitting.
+//	Sub.LogLevel -> SUB_LOG_LEVEL, SUB_LOGLEVEL
+func envNames(path []string) []string {
+	names := []string{envName(path)}
+	plain := strings.ToUpper(strings.Join(path, "_"))
+	if plain != names[0] {
+		names = append(names, plain)
+	}
+	return names
+}
+
+// splitWords breaks a camel case identifier into words, keeping acronyms together.
+//	HTTPServerPort -> HTTP, Server, Port
+func splitWords(s string) []string {
+	r := []rune(s)
+	var words []string
+	start := 0
+	for i := 1; i < len(r); i++ {
+		if !unicode.IsUpper(r[i]) {
+			continue
+		}
+		prevLower := unicode.IsLower(r[i-1]) || unicode.IsDigit(r[i-1])
+		nextLower := i+1 < len(r) && unicode.IsLower(r[i+1])
+		if prevLower || (unicode.IsUpper(r[i-1]) && nextLower) {
+			words = append(words, string(r[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(r[start:]))
+}
+
+// envPrefix normalizes a prefix from WithEnvPrefix into upper case with a trailing underscore, or "" for no prefix.
+func envPrefix(prefix string) string {
+	prefix = strings.TrimSuffix(strings.ToUpper(prefix), "_")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "_"
+}
+
+// fieldEnvNames returns the environment variable names accepted for a member, preferred first.
+// An `env:"NAME"` tag overrides the generated names and ignores the prefix.
+func fieldEnvNames(f field, prefix string) []string {
+	if name := tagName(f.sf, "env"); name != "" {
+		return []string{name}
+	}
+	names := envNames(f.path)
+	for i := range names {
+		names[i] = envPrefix(prefix) + names[i]
+	}
+	return names
+}
+
+// applyVars sets every leaf member of v that lookup finds a variable for, trying each of its fieldEnvNames in turn.
+// A nil pointer to a nested struct is allocated when any of the variables for its members is found.
+// Values are converted with UnmarshalValue, and every failure is returned together as Errors.
+func applyVars(v interface{}, layer Layer, prefix string, lookup func(name string) (string, Origin, bool), p Provenance) error {
+	var errs Errors
+	found := 0
+	var apply func(f field) error
+	apply = func(f field) error {
+		if typ := f.value.Type(); typ.Kind() == reflect.Ptr && f.value.IsNil() && isNestedType(typ.Elem()) && !recursive(f) {
+			// fill in a fresh struct, and keep it only if anything was found for it
+			before := found
+			nv := reflect.New(typ.Elem())
+			walkStruct(nv.Elem(), f.path, append(append([]reflect.StructField{}, f.parents...), f.sf), apply)
+			if found > before {
+				f.value.Set(nv)
+				return nil
+			}
+		}
+
+		for _, name := range fieldEnvNames(f, prefix) {
+			value, o, ok := lookup(name)
+			if !ok {
+				continue
+			}
+			found++
+			if err := unmarshalField(value, f.value, f.sf); err != nil {
+				errs.add(layer, f.Path(), name+"="+value, err)
+			} else {
+				p.setValue(f.Path(), o, value, f.value.Type())
+			}
+			break
+		}
+		return nil
+	}
+	err := walkFields(v, apply)
+	errs.add(layer, "", "", err)
+	return errs.err()
+}
+
+// recursive reports whether a pointer member points to the type of a struct it is nested in,
+// which would have variables for ever deeper paths.
+func recursive(f field) bool {
+	elem := f.sf.Type.Elem()
+	for _, sf := range f.parents {
+		if indirectType(sf.Type) == elem {
+			return true
+		}
+	}
+	return false
+}
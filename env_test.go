@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "ADDRESS", envName([]string{"Address"}))
+	assert.Equal(t, "SUB_LEVEL", envName([]string{"Sub", "Level"}))
+	assert.Equal(t, "SUB_LOG_LEVEL", envName([]string{"Sub", "LogLevel"}))
+	assert.Equal(t, "HTTP_SERVER_PORT", envName([]string{"HTTPServerPort"}))
+}
+
+func TestEnvNames(t *testing.T) {
+	assert.Equal(t, []string{"SUB_LEVEL"}, envNames([]string{"Sub", "Level"}))
+	assert.Equal(t, []string{"SUB_LOG_LEVEL", "SUB_LOGLEVEL"}, envNames([]string{"Sub", "LogLevel"}))
+}
+
+func TestFieldEnvNames(t *testing.T) {
+	type tagged struct {
+		Level int
+		Token string `env:"API_TOKEN"`
+	}
+	var names [][]string
+	walkFields(&tagged{}, func(f field) error {
+		names = append(names, fieldEnvNames(f, "myapp_"))
+		return nil
+	})
+	assert.Equal(t, [][]string{{"MYAPP_LEVEL"}, {"API_TOKEN"}}, names)
+	assert.Equal(t, "MYAPP_", envPrefix("MyApp"))
+	assert.Equal(t, "", envPrefix(""))
+}
+
+func TestSplitWords(t *testing.T) {
+	assert.Equal(t, []string{"Address"}, splitWords("Address"))
+	assert.Equal(t, []string{"Log", "Level"}, splitWords("LogLevel"))
+	assert.Equal(t, []string{"HTTP", "Server", "Port"}, splitWords("HTTPServerPort"))
+	assert.Equal(t, []string{"Port2", "Go"}, splitWords("Port2Go"))
+	assert.Equal(t, []string{"ID"}, splitWords("ID"))
+}
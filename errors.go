@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layer names the configuration source that supplied or failed to supply a setting.
+type Layer string
+
+const (
+	// LayerDefaults is the "default:" struct tag values
+	LayerDefaults Layer = "defaults"
+	// LayerFile is a configuration file such as config.yml
+	LayerFile Layer = "file"
+	// LayerEnvironment is the process environment variables
+	LayerEnvironment Layer = "env"
+	// LayerArguments is the command line arguments
+	LayerArguments Layer = "args"
+)
+
+// SourceError describes a failure to apply a setting from one configuration layer.
+// Path is the struct field path when it is known, and Input is the raw text that failed,
+// such as a file name, an environment variable value, or a command line argument.
+type SourceError struct {
+	Layer Layer
+	Path  string
+	Input string
+	Err   error
+}
+
+// Error formats the failure as "layer: path: input: cause", omitting any empty parts.
+func (e *SourceError) Error() string {
+	parts := []string{string(e.Layer)}
+	if e.Path != "" {
+		parts = append(parts, e.Path)
+	}
+	if e.Input != "" {
+		parts = append(parts, fmt.Sprintf("%q", e.Input))
+	}
+	parts = append(parts, e.Err.Error())
+	return strings.Join(parts, ": ")
+}
+
+// Unwrap returns the underlying cause so errors.Is and errors.As can inspect it.
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// Errors aggregates every failure encountered while loading configuration.
+type Errors []error
+
+// Error joins the individual failures, one per line.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// add appends err, flattening nested Errors and wrapping bare errors in a SourceError for layer.
+func (e *Errors) add(layer Layer, path, input string, err error) {
+	switch t := err.(type) {
+	case nil:
+		return
+	case Errors:
+		*e = append(*e, t...)
+	case *SourceError:
+		*e = append(*e, t)
+	default:
+		*e = append(*e, &SourceError{Layer: layer, Path: path, Input: input, Err: err})
+	}
+}
+
+// err returns nil when nothing was collected, so callers can return it directly.
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceErrorMessage(t *testing.T) {
+	err := &SourceError{Layer: LayerArguments, Path: "Sub.Level", Input: "Sub.Level=high", Err: strconv.ErrSyntax}
+	assert.Equal(t, `args: Sub.Level: "Sub.Level=high": invalid syntax`, err.Error())
+
+	err = &SourceError{Layer: LayerEnvironment, Err: strconv.ErrRange}
+	assert.Equal(t, "env: value out of range", err.Error())
+}
+
+func TestSourceErrorUnwrap(t *testing.T) {
+	err := &SourceError{Layer: LayerArguments, Err: ErrBadMap}
+	assert.True(t, errors.Is(err, ErrBadMap))
+}
+
+// nested Errors are flattened and bare errors are wrapped
+func TestErrorsAdd(t *testing.T) {
+	var errs Errors
+	errs.add(LayerFile, "", "", nil)
+	assert.Nil(t, errs.err())
+
+	errs.add(LayerFile, "", "config.yml", strconv.ErrSyntax)
+	errs.add(LayerArguments, "", "", Errors{
+		&SourceError{Layer: LayerArguments, Path: "A", Err: strconv.ErrSyntax},
+		&SourceError{Layer: LayerArguments, Path: "B", Err: strconv.ErrRange},
+	})
+	assert.Equal(t, 3, len(errs))
+	assert.Equal(t, LayerFile, errs[0].(*SourceError).Layer)
+	assert.Equal(t, "B", errs[2].(*SourceError).Path)
+	assert.Equal(t, "file: \"config.yml\": invalid syntax\nargs: A: invalid syntax\nargs: B: value out of range", errs.Error())
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	config "github.com/335is/config"
+)
+
+type cfg struct {
+	Address string        `yaml:"address"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func main() {
+	c := cfg{}
+	config.Load("./cfg.json", &c)
+	s, _ := config.ToJson(&c)
+	fmt.Printf("%s\n", s)
+}
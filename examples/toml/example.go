@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	config "github.com/335is/config"
+)
+
+type cfg1 struct {
+	Address string        `yaml:"address"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type cfg2 struct {
+	HTTP cfg1
+}
+
+func main() {
+	c2 := cfg2{}
+	config.Load("./cfg2.toml", &c2)
+	s2, _ := config.ToToml(&c2)
+	fmt.Printf("%s\n", s2)
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnknownField indicates that no struct member matches a key path
+	ErrUnknownField = errors.New("no struct member matches the key path")
+	// ErrBadIndex indicates a key path [index] that is malformed, out of range, or not on a slice
+	ErrBadIndex = errors.New("invalid slice index in key path")
+)
+
+// field is one exported leaf member of a config struct, located by its path from the root.
+type field struct {
+	path  []string
+	value reflect.Value
+	sf    reflect.StructField
+	// parents are the nested struct members leading to sf, outermost first
+	parents []reflect.StructField
+}
+
+// Path returns the period separated Go field names leading to the member, such as "Sub.Level".
+func (f field) Path() string {
+	return joinPath(f.path)
+}
+
+// Key returns the period separated document key for the member, such as "sub.level",
+// using the name in the first of tags that has one or else the lower cased Go field name.
+func (f field) Key(tags ...string) string {
+	keys := make([]string, 0, len(f.parents)+1)
+	for _, sf := range append(append([]reflect.StructField{}, f.parents...), f.sf) {
+		keys = append(keys, keyName(sf, tags))
+	}
+	return joinPath(keys)
+}
+
+// joinPath joins Go field names into a period separated member path.
+func joinPath(path []string) string {
+	return strings.Join(path, ".")
+}
+
+// walkFields calls fn for every exported leaf member of the struct pointed to by v,
+// descending into nested structs and non-nil pointers to them. It stops at the first error returned by fn.
+func walkFields(v interface{}, fn func(f field) error) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return ErrNilPointer
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return ErrInvalidType
+	}
+	return walkStruct(rv, nil, nil, fn)
+}
+
+func walkStruct(rv reflect.Value, path []string, parents []reflect.StructField, fn func(f field) error) error {
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		p := append(append([]string{}, path...), sf.Name)
+		fv := rv.Field(i)
+		if sv, ok := nested(fv); ok {
+			if err := walkStruct(sv, p, append(append([]reflect.StructField{}, parents...), sf), fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := fn(field{path: p, value: fv, sf: sf, parents: parents}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nested returns the struct that a member holds settings of its own in, following a non-nil pointer,
+// or false if the member is a single value. A nil pointer is a single value until something allocates it.
+func nested(rv reflect.Value) (reflect.Value, bool) {
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && isNestedType(rv.Type().Elem()) {
+		return rv.Elem(), true
+	}
+	return rv, isNestedType(rv.Type())
+}
+
+// indirectType returns the type a pointer type points to, or typ itself if it isn't a pointer.
+func indirectType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		return typ.Elem()
+	}
+	return typ
+}
+
+// isNestedType reports whether members of type typ hold settings of their own.
+// Structs that decode from a single string, such as time.Time, are single values.
+func isNestedType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && !hasDecoder(typ)
+}
+
+// resolve finds the struct member named by a period separated key path such as "sub.level", "tags[2]",
+// or "limits.cpu" for the cpu entry of a map member. Names match the Go field name or its yaml tag name,
+// ignoring case, while map keys are converted to the map's key type and match exactly.
+// Nil pointers to nested structs along the path are allocated, and nil maps once an entry is stored.
+// It returns the member along with its canonical path of Go field names and map keys, and a function
+// that must be called once the member is set, which writes map entries back into their maps.
+func resolve(v interface{}, key string) (reflect.Value, string, func(), error) {
+	rv, _, path, store, err := resolveField(v, key)
+	return rv, path, store, err
+}
+
+// resolveField is resolve that also returns the struct field the key path ends in, for its tags.
+// For an indexed path such as "tags[2]" or a map entry such as "limits.cpu" it is the field holding the slice or map.
+func resolveField(v interface{}, key string) (reflect.Value, reflect.StructField, string, func(), error) {
+	rv := reflect.ValueOf(v)
+	var sf reflect.StructField
+	var path []string
+
+	// nil struct pointers along the way are allocated, and put back if the key doesn't resolve
+	var allocated []reflect.Value
+	fail := func(err error) (reflect.Value, reflect.StructField, string, func(), error) {
+		for _, ptr := range allocated {
+			ptr.Set(reflect.Zero(ptr.Type()))
+		}
+		return reflect.Value{}, reflect.StructField{}, "", nil, err
+	}
+
+	// map entries aren't addressable, so they are resolved to copies that are stored back innermost first
+	var stores []func()
+	store := func() {
+		for i := len(stores) - 1; i >= 0; i-- {
+			stores[i]()
+		}
+	}
+
+	for _, part := range strings.Split(key, ".") {
+		name, index, err := parseIndex(part)
+		if err != nil {
+			return fail(err)
+		}
+
+		if rv.Kind() == reflect.Ptr && rv.IsNil() && rv.CanSet() && rv.Type().Elem().Kind() == reflect.Struct {
+			rv.Set(reflect.New(rv.Type().Elem()))
+			allocated = append(allocated, rv)
+		}
+		rv = reflect.Indirect(rv)
+		switch rv.Kind() {
+		case reflect.Struct:
+			var ok bool
+			sf, ok = findField(rv.Type(), name)
+			if !ok {
+				return fail(ErrUnknownField)
+			}
+			rv = rv.FieldByIndex(sf.Index)
+			path = append(path, sf.Name)
+
+		case reflect.Map:
+			typ := rv.Type()
+			k := reflect.New(typ.Key()).Elem()
+			if err := UnmarshalValue(name, k); err != nil {
+				return fail(ErrUnknownField)
+			}
+			if rv.IsNil() && !rv.CanSet() {
+				return fail(ErrUnknownField)
+			}
+			m, entry := rv, reflect.New(typ.Elem()).Elem()
+			if existing := m.MapIndex(k); existing.IsValid() {
+				entry.Set(existing)
+			}
+			stores = append(stores, func() {
+				if m.IsNil() {
+					m.Set(reflect.MakeMap(typ))
+				}
+				m.SetMapIndex(k, entry)
+			})
+			rv = entry
+			path = append(path, name)
+
+		default:
+			return fail(ErrUnknownField)
+		}
+
+		if index >= 0 {
+			rv = reflect.Indirect(rv)
+			if rv.Kind() != reflect.Slice || index >= rv.Len() {
+				return fail(ErrBadIndex)
+			}
+			rv = rv.Index(index)
+			path[len(path)-1] += "[" + strconv.Itoa(index) + "]"
+		}
+	}
+
+	return rv, sf, joinPath(path), store, nil
+}
+
+// findField returns the exported member of struct type typ whose Go field name
+// or name in any of the given tags matches name, ignoring case. Tags default to yaml.
+func findField(typ reflect.Type, name string, tags ...string) (reflect.StructField, bool) {
+	if len(tags) == 0 {
+		tags = []string{"yaml"}
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		if strings.EqualFold(sf.Name, name) {
+			return sf, true
+		}
+		for _, tag := range tags {
+			if tn := tagName(sf, tag); tn != "" && strings.EqualFold(tn, name) {
+				return sf, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// tagName returns the name part of a struct tag such as `yaml:"name,omitempty"`, or "" if there is none.
+func tagName(sf reflect.StructField, key string) string {
+	name := strings.Split(sf.Tag.Get(key), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// parseIndex splits a key path part like "tags[2]" into its name and index, returning -1 if there is no index.
+func parseIndex(part string) (string, int, error) {
+	open := strings.Index(part, "[")
+	if open < 0 {
+		return part, -1, nil
+	}
+	if !strings.HasSuffix(part, "]") {
+		return "", -1, ErrBadIndex
+	}
+	index, err := strconv.Atoi(part[open+1 : len(part)-1])
+	if err != nil || index < 0 {
+		return "", -1, ErrBadIndex
+	}
+	return part[:open], index, nil
+}
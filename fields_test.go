@@ -0,0 +1,112 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testFields struct {
+	Address string `yaml:"addr"`
+	Tags    []string
+	Sub     SubNested
+	hidden  int
+}
+
+// every exported leaf member is visited with its full path
+func TestWalkFields(t *testing.T) {
+	var paths []string
+	err := walkFields(&testFields{}, func(f field) error {
+		paths = append(paths, f.Path())
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Address", "Tags", "Sub.Enabled", "Sub.Level"}, paths)
+}
+
+func TestWalkFieldsNotStruct(t *testing.T) {
+	i := 0
+	err := walkFields(&i, func(f field) error { return nil })
+	assert.Equal(t, ErrInvalidType, err)
+}
+
+// key paths match Go names or yaml tag names, ignoring case
+func TestResolve(t *testing.T) {
+	c := testFields{Tags: []string{"a", "b"}}
+
+	rv, path, store, err := resolve(&c, "ADDR")
+	assert.Nil(t, err)
+	assert.Equal(t, "Address", path)
+	rv.SetString("here")
+	store()
+	assert.Equal(t, "here", c.Address)
+
+	_, path, _, err = resolve(&c, "sub.level")
+	assert.Nil(t, err)
+	assert.Equal(t, "Sub.Level", path)
+
+	rv, path, _, err = resolve(&c, "tags[1]")
+	assert.Nil(t, err)
+	assert.Equal(t, "Tags[1]", path)
+	assert.Equal(t, "b", rv.String())
+}
+
+type testMapFields struct {
+	Limits  map[string]int
+	Servers map[string]SubNested
+	Ports   map[int]string
+}
+
+// key paths continue into map members by key, and map entries are stored once they are set
+func TestResolveMapKey(t *testing.T) {
+	c := testMapFields{Limits: map[string]int{"cpu": 2}}
+
+	rv, path, store, err := resolve(&c, "limits.mem")
+	assert.Nil(t, err)
+	assert.Equal(t, "Limits.mem", path)
+	rv.SetInt(512)
+	store()
+	assert.Equal(t, map[string]int{"cpu": 2, "mem": 512}, c.Limits)
+
+	rv, path, store, err = resolve(&c, "servers.web.level")
+	assert.Nil(t, err)
+	assert.Equal(t, "Servers.web.Level", path)
+	rv.SetInt(3)
+	store()
+	assert.Equal(t, 3, c.Servers["web"].Level)
+
+	// an entry isn't added until it is stored, and keys must convert to the key type
+	_, _, _, err = resolve(&c, "ports.80")
+	assert.Nil(t, err)
+	assert.Nil(t, c.Ports)
+	_, _, _, err = resolve(&c, "ports.http")
+	assert.Equal(t, ErrUnknownField, err)
+
+	err = FromArguments([]string{"limits.cpu=4", "--servers.db.enabled", "ports.80=http"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, c.Limits["cpu"])
+	assert.True(t, c.Servers["db"].Enabled)
+	assert.Equal(t, map[int]string{80: "http"}, c.Ports)
+}
+
+func TestResolveErrors(t *testing.T) {
+	c := testFields{Tags: []string{"a"}}
+
+	_, _, _, err := resolve(&c, "nowhere")
+	assert.Equal(t, ErrUnknownField, err)
+
+	_, _, _, err = resolve(&c, "hidden")
+	assert.Equal(t, ErrUnknownField, err)
+
+	_, _, _, err = resolve(&c, "address.more")
+	assert.Equal(t, ErrUnknownField, err)
+
+	_, _, _, err = resolve(&c, "tags[3]")
+	assert.Equal(t, ErrBadIndex, err)
+
+	_, _, _, err = resolve(&c, "tags[x]")
+	assert.Equal(t, ErrBadIndex, err)
+
+	_, _, _, err = resolve(&c, "address[0]")
+	assert.Equal(t, ErrBadIndex, err)
+}
@@ -1,11 +1,10 @@
 module github.com/335is/config
 
-go 1.15
+go 1.16
 
 require (
-	github.com/creasty/defaults v1.5.2
-	github.com/mcuadros/go-lookup v0.0.0-20200831155250-80f87a4fa5ee
+	github.com/BurntSushi/toml v1.2.0
 	github.com/stretchr/testify v1.7.0
-	github.com/vrischmann/envconfig v1.3.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// ErrHelp is returned by FromArguments when the arguments ask for help with --help or -h,
+// unless the struct has a member of that name.
+var ErrHelp = errors.New("help requested")
+
+// exit ends the process after Load or MustLoad prints usage, and is replaced in tests.
+var exit = os.Exit
+
+// Usage writes a description of every setting in the struct pointed to by v, one line each,
+// giving its argument path or <position> after any subcommand, type, "default:" tag, environment variable, and "desc:" tag.
+// Load and MustLoad print it to stdout and exit when the command line asks for --help.
+//	Usage: myapp [--key.path value | key.path=value]...
+//
+//	  --address     string          default "http://example.com"   env ADDRESS     where to listen
+//	  --sub.level   int             default "77"                   env SUB_LEVEL
+func Usage(w io.Writer, v interface{}, opts ...Option) error {
+	prefix := newOptions(opts).envPrefix
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Usage: %s [--key.path value | key.path=value]...\n\n", filepath.Base(os.Args[0]))
+
+	tw := tabwriter.NewWriter(&b, 0, 4, 3, ' ', 0)
+	err := walkFields(v, func(f field) error {
+		def := ""
+		if tag, ok := f.sf.Tag.Lookup("default"); ok {
+			def = "default " + strconv.Quote(tag)
+		}
+		cmds, key := commandKey(f, "yaml")
+		name := "--" + key
+		if tag, ok := f.sf.Tag.Lookup("arg"); ok {
+			name = "<" + key + ">"
+			if tag == "rest" {
+				name += "..."
+			}
+		}
+		if len(cmds) > 0 {
+			name = strings.Join(cmds, " ") + " " + name
+		}
+		_, err := fmt.Fprintf(tw, "  %s\t%s\t%s\tenv %s\t%s\n",
+			name, f.sf.Type, def, fieldEnvNames(f, prefix)[0], f.sf.Tag.Get("desc"))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// members without a description would otherwise end in padding
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	_, err = io.WriteString(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// helpRequested reports whether err, or any of the Errors it holds, is ErrHelp.
+func helpRequested(err error) bool {
+	if errs, ok := err.(Errors); ok {
+		for _, e := range errs {
+			if helpRequested(e) {
+				return true
+			}
+		}
+		return false
+	}
+	return errors.Is(err, ErrHelp)
+}
+
+// exitForHelp prints usage for v and exits the process when err shows that help was requested.
+func exitForHelp(err error, v interface{}, opts []Option) {
+	if helpRequested(err) {
+		Usage(os.Stdout, v, opts...)
+		exit(0)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestHelp struct {
+	Address string        `yaml:"addr" default:"http://example.com" desc:"where to listen"`
+	Timeout time.Duration `default:"1m"`
+	Sub     SubNested
+	Files   []string `arg:"rest"`
+}
+
+func TestUsage(t *testing.T) {
+	os.Args = []string{"myapp"}
+	var b bytes.Buffer
+	err := Usage(&b, &TestHelp{}, WithEnvPrefix("myapp"))
+	assert.Nil(t, err)
+	assert.Equal(t, `Usage: myapp [--key.path value | key.path=value]...
+
+  --addr          string          default "http://example.com"   env MYAPP_ADDRESS       where to listen
+  --timeout       time.Duration   default "1m"                   env MYAPP_TIMEOUT
+  --sub.enabled   bool            default "true"                 env MYAPP_SUB_ENABLED
+  --sub.level     int             default "77"                   env MYAPP_SUB_LEVEL
+  <files>...      []string                                       env MYAPP_FILES
+`, b.String())
+}
+
+func TestFromArgumentsHelp(t *testing.T) {
+	assert.Equal(t, ErrHelp, FromArguments([]string{"timeout=1m", "-h"}, &TestHelp{}))
+	assert.Equal(t, ErrHelp, FromArguments([]string{"--help"}, &TestHelp{}))
+	assert.Nil(t, FromArguments([]string{"--", "--help"}, &TestHelp{}))
+
+	// a member named help is set like any other
+	type withHelp struct {
+		Help bool
+	}
+	cfg := withHelp{}
+	assert.Nil(t, FromArguments([]string{"--help"}, &cfg))
+	assert.True(t, cfg.Help)
+}
+
+func TestLoadHelpExits(t *testing.T) {
+	code := -1
+	exit = func(c int) { code = c }
+	stdout := os.Stdout
+	os.Stdout, _ = os.Open(os.DevNull)
+	defer func() {
+		exit = os.Exit
+		os.Stdout = stdout
+	}()
+
+	os.Args = []string{"myapp", "--help"}
+	err := LoadE("bogus_file_name.yml", &TestHelp{})
+	assert.True(t, helpRequested(err))
+	assert.Equal(t, -1, code)
+
+	Load("bogus_file_name.yml", &TestHelp{})
+	assert.Equal(t, 0, code)
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrBadIniLine indicates an INI line that is neither a [section] nor a key=value pair
+	ErrBadIniLine = errors.New("ini line must be [section] or key=value")
+	// ErrBadPropertiesLine indicates a properties line without a key
+	ErrBadPropertiesLine = errors.New("properties line must be key=value")
+)
+
+// keyValue is one setting read from a flat file, keyed by its period separated member path.
+type keyValue struct {
+	key   string
+	value string
+	line  int
+}
+
+// FromIni extracts settings from the contents of an INI file.
+// Each [section] name prefixes the keys beneath it, so key.path matching works just like FromArguments.
+// Lines starting with ; or # are comments, and values may be wrapped in quotes.
+//	[server]
+//	address = http://example.com
+func FromIni(ini []byte, v interface{}) error {
+	return applyIni(ini, "", v, nil)
+}
+
+// FromIniFile extracts settings from an INI file.
+func FromIniFile(path string, v interface{}) error {
+	return applyIniFile(path, v, nil)
+}
+
+func applyIniFile(path string, v interface{}, p Provenance) error {
+	ini, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return applyIni(ini, path, v, p)
+}
+
+func applyIni(ini []byte, path string, v interface{}, p Provenance) error {
+	kvs, err := parseIni(ini)
+	if err != nil {
+		return err
+	}
+	return applyKeyValues(kvs, path, v, p)
+}
+
+// parseIni reads every key=value pair from an INI file, prefixing keys with their section.
+func parseIni(ini []byte) ([]keyValue, error) {
+	var kvs []keyValue
+	section := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(ini))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if !strings.HasSuffix(text, "]") {
+				return nil, &SourceError{Layer: LayerFile, Input: scanner.Text(), Err: ErrBadIniLine}
+			}
+			section = strings.TrimSpace(text[1 : len(text)-1])
+			continue
+		}
+
+		eq := strings.IndexAny(text, "=:")
+		if eq < 1 {
+			return nil, &SourceError{Layer: LayerFile, Input: scanner.Text(), Err: ErrBadIniLine}
+		}
+		key := strings.TrimSpace(text[:eq])
+		if section != "" {
+			key = section + "." + key
+		}
+		kvs = append(kvs, keyValue{key: key, value: unquote(strings.TrimSpace(text[eq+1:])), line: line})
+	}
+
+	return kvs, scanner.Err()
+}
+
+// unquote removes one pair of matching single or double quotes surrounding s.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// FromProperties extracts settings from the contents of a Java style .properties file.
+// Dotted keys map onto nested struct members just like FromArguments.
+// Keys and values are separated by =, :, or whitespace, lines starting with # or ! are comments,
+// and a trailing backslash continues a value on the next line.
+//	server.address = http://example.com
+func FromProperties(props []byte, v interface{}) error {
+	return applyProperties(props, "", v, nil)
+}
+
+// FromPropertiesFile extracts settings from a .properties file.
+func FromPropertiesFile(path string, v interface{}) error {
+	return applyPropertiesFile(path, v, nil)
+}
+
+func applyPropertiesFile(path string, v interface{}, p Provenance) error {
+	props, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return applyProperties(props, path, v, p)
+}
+
+func applyProperties(props []byte, path string, v interface{}, p Provenance) error {
+	kvs, err := parseProperties(props)
+	if err != nil {
+		return err
+	}
+	return applyKeyValues(kvs, path, v, p)
+}
+
+// parseProperties reads every key=value pair from a .properties file.
+func parseProperties(props []byte) ([]keyValue, error) {
+	var kvs []keyValue
+
+	scanner := bufio.NewScanner(bytes.NewReader(props))
+	for line := 1; scanner.Scan(); line++ {
+		start := line
+		text := strings.TrimLeft(scanner.Text(), " \t\f")
+		if text == "" || text[0] == '#' || text[0] == '!' {
+			continue
+		}
+
+		// join continuation lines, dropping leading whitespace from each
+		for continues(text) && scanner.Scan() {
+			line++
+			text = text[:len(text)-1] + strings.TrimLeft(scanner.Text(), " \t\f")
+		}
+
+		key, value := splitProperty(text)
+		if key == "" {
+			return nil, &SourceError{Layer: LayerFile, Input: text, Err: ErrBadPropertiesLine}
+		}
+		kvs = append(kvs, keyValue{key: unescapeProperty(key), value: unescapeProperty(value), line: start})
+	}
+
+	return kvs, scanner.Err()
+}
+
+// continues reports whether a properties line ends in an odd number of backslashes.
+func continues(text string) bool {
+	n := 0
+	for i := len(text) - 1; i >= 0 && text[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty separates a properties line at the first unescaped =, :, or whitespace.
+func splitProperty(text string) (string, string) {
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '=', ':', ' ', '\t', '\f':
+			key := text[:i]
+			rest := strings.TrimLeft(text[i:], " \t\f")
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+				rest = strings.TrimLeft(rest[1:], " \t\f")
+			}
+			return key, rest
+		}
+	}
+	return text, ""
+}
+
+// unescapeProperty resolves backslash escapes, including \uXXXX, in a properties key or value.
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// applyKeyValues sets the struct member named by each key path, converting values with UnmarshalValue.
+// Keys that match no member are ignored, as with the other file formats.
+func applyKeyValues(kvs []keyValue, file string, v interface{}, p Provenance) error {
+	var errs Errors
+	for _, kv := range kvs {
+		value, sf, path, store, err := resolveField(v, kv.key)
+		if err == ErrUnknownField {
+			continue
+		}
+		if err != nil {
+			errs.add(LayerFile, kv.key, kv.value, err)
+			continue
+		}
+		if err := unmarshalField(kv.value, value, sf); err != nil {
+			errs.add(LayerFile, path, kv.value, err)
+			continue
+		}
+		store()
+		p.setValue(path, Origin{Layer: LayerFile, Name: file, Line: kv.line}, kv.value, value.Type())
+	}
+	return errs.err()
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ini = `; appliance settings
+address = "http://example.com/"
+timeout: 1m
+
+[sub]
+enabled = true
+level = 42
+vendor = ignored
+`
+
+// parse an INI string, with sections mapping onto nested structs
+func TestFromIni(t *testing.T) {
+	cfg := TestArguments{}
+	err := FromIni([]byte(ini), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://example.com/", cfg.Address)
+	assert.Equal(t, time.Minute, cfg.Timeout)
+	assert.Equal(t, true, cfg.Sub.Enabled)
+	assert.Equal(t, 42, cfg.Sub.Level)
+}
+
+func TestFromIniBad(t *testing.T) {
+	cfg := TestArguments{}
+	err := FromIni([]byte("[sub\nlevel=1\n"), &cfg)
+	assert.Equal(t, ErrBadIniLine, err.(*SourceError).Err)
+
+	err = FromIni([]byte("just words\n"), &cfg)
+	assert.Equal(t, ErrBadIniLine, err.(*SourceError).Err)
+
+	err = FromIni([]byte("[sub]\nlevel=high\n"), &cfg)
+	serr := err.(Errors)[0].(*SourceError)
+	assert.Equal(t, "Sub.Level", serr.Path)
+	assert.Equal(t, "high", serr.Input)
+}
+
+// parsing a good INI file succeeds and records lines
+func TestFromIniFile(t *testing.T) {
+	file, err := ioutil.TempFile(".", "ini_test*.ini")
+	assert.Nil(t, err, "Got error trying to create temporary INI file")
+	defer os.Remove(file.Name())
+	file.Write([]byte(ini))
+	file.Close()
+
+	cfg := TestArguments{}
+	err = FromIniFile(file.Name(), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 42, cfg.Sub.Level)
+
+	os.Args = []string{"test"}
+	cfg = TestArguments{}
+	p, err := Trace(file.Name(), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 42, cfg.Sub.Level)
+	assert.Equal(t, Origin{Layer: LayerFile, Name: file.Name(), Line: 7}, p["Sub.Level"])
+}
+
+var props = `# appliance settings
+! also a comment
+address = http://example.com/
+timeout : 1m
+sub.enabled true
+sub.level = 4\
+  2
+`
+
+// parse a properties string, with dotted keys mapping onto nested structs
+func TestFromProperties(t *testing.T) {
+	cfg := TestArguments{}
+	err := FromProperties([]byte(props), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://example.com/", cfg.Address)
+	assert.Equal(t, time.Minute, cfg.Timeout)
+	assert.Equal(t, true, cfg.Sub.Enabled)
+	assert.Equal(t, 42, cfg.Sub.Level)
+}
+
+func TestParseProperties(t *testing.T) {
+	kvs, err := parseProperties([]byte("a\\=b = c\\td\nname=\\u0041\\u00e9\nempty\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []keyValue{
+		{key: "a=b", value: "c\td", line: 1},
+		{key: "name", value: "Aé", line: 2},
+		{key: "empty", value: "", line: 3},
+	}, kvs)
+
+	_, err = parseProperties([]byte("=value\n"))
+	assert.Equal(t, ErrBadPropertiesLine, err.(*SourceError).Err)
+}
+
+// parsing a good properties file succeeds
+func TestFromPropertiesFile(t *testing.T) {
+	file, err := ioutil.TempFile(".", "properties_test*.properties")
+	assert.Nil(t, err, "Got error trying to create temporary properties file")
+	defer os.Remove(file.Name())
+	file.Write([]byte(props))
+	file.Close()
+
+	cfg := TestArguments{}
+	err = FromPropertiesFile(file.Name(), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 42, cfg.Sub.Level)
+
+	err = FromPropertiesFile("bogus_file_name", &cfg)
+	assert.NotNil(t, err)
+}
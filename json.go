@@ -0,0 +1,61 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+)
+
+// jsonTags are the struct tags consulted for JSON key names, falling back to the yaml names so one struct serves both.
+var jsonTags = []string{"json", "yaml"}
+
+// FromJson extracts settings from a JSON string.
+// Keys match the json tag name, then the yaml tag name, then the Go field name, ignoring case.
+func FromJson(js []byte, v interface{}) error {
+	tree, err := parseJson(js)
+	if err != nil {
+		return err
+	}
+	return decodeTree(tree, reflect.ValueOf(v), jsonTags)
+}
+
+// FromJsonFile extracts settings from a JSON file.
+func FromJsonFile(path string, v interface{}) error {
+	return applyJsonFile(path, v, nil)
+}
+
+func applyJsonFile(path string, v interface{}, p Provenance) error {
+	js, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = FromJson(js, v)
+	if err == nil && p != nil {
+		tree, _ := parseJson(js)
+		traceTree(tree, reflect.TypeOf(v), jsonTags, nil, path, p)
+	}
+	return err
+}
+
+// parseJson decodes a JSON document into a generic tree, keeping numbers as their original text.
+func parseJson(js []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+
+	var tree interface{}
+	err := dec.Decode(&tree)
+	return tree, err
+}
+
+// ToJson marshals the struct into an indented JSON string, naming keys the same way FromJson matches them.
+// Members tagged `secret:"true"` or of type Secret are written as ******.
+func ToJson(v interface{}) (string, error) {
+	buff, err := json.MarshalIndent(encodeTree(reflect.ValueOf(Redacted(v)), jsonTags), "", "  ")
+	if err == nil {
+		return string(buff) + "\n", nil
+	}
+
+	return "", err
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestJson struct {
+	Address string        `json:"url" yaml:"address"`
+	Count   int           `yaml:"count"`
+	Passive bool          `json:"passive,omitempty"`
+	Period  time.Duration `yaml:"period"`
+	Sub     SubNested     `yaml:"sub"`
+	Tags    []string      `yaml:"tags"`
+	Skipped string        `json:"-"`
+}
+
+var js = `{
+  "url": "http://example.com/",
+  "count": 23,
+  "period": "2m22s",
+  "sub": {
+    "enabled": true,
+    "level": 12
+  },
+  "tags": [
+    "red",
+    "blue"
+  ]
+}
+`
+
+// parsing a good JSON string succeeds, using json tags, then yaml tags, then field names
+func TestFromJson(t *testing.T) {
+	cfg := TestJson{}
+	err := FromJson([]byte(js), &cfg)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, "http://example.com/", cfg.Address)
+	assert.Equal(t, 23, cfg.Count)
+	assert.Equal(t, false, cfg.Passive)
+	assert.Equal(t, (2*time.Minute)+(22*time.Second), cfg.Period)
+	assert.Equal(t, true, cfg.Sub.Enabled)
+	assert.Equal(t, 12, cfg.Sub.Level)
+	assert.Equal(t, []string{"red", "blue"}, cfg.Tags)
+}
+
+// keys are matched ignoring case, and "-" members are never set
+func TestFromJsonKeyMatching(t *testing.T) {
+	cfg := TestJson{}
+	err := FromJson([]byte(`{"ADDRESS": "http://a.com/", "Passive": true, "skipped": "no"}`), &cfg)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, "http://a.com/", cfg.Address)
+	assert.Equal(t, true, cfg.Passive)
+	assert.Equal(t, "", cfg.Skipped)
+}
+
+// parsing a bad JSON string fails
+func TestFromJsonBad(t *testing.T) {
+	cfg := TestJson{}
+	err := FromJson([]byte(notYml), &cfg)
+	assert.NotNil(t, err, "Expected an error because of bad JSON")
+	assert.Equal(t, "", cfg.Address)
+}
+
+// a value that doesn't convert reports the member path and raw text
+func TestFromJsonBadValue(t *testing.T) {
+	cfg := TestJson{}
+	err := FromJson([]byte(`{"sub": {"level": "high"}}`), &cfg)
+	serr, ok := err.(*SourceError)
+	assert.True(t, ok, "Expected *SourceError")
+	assert.Equal(t, "Sub.Level", serr.Path)
+	assert.Equal(t, "high", serr.Input)
+
+	err = FromJson([]byte(`{"tags": {"a": "b"}}`), &cfg)
+	assert.Equal(t, ErrTreeMismatch, err.(*SourceError).Err)
+}
+
+// parsing a good JSON file succeeds
+func TestFromJsonFile(t *testing.T) {
+	file, err := ioutil.TempFile(".", "json_test")
+	assert.Nil(t, err, "Got error trying to create temporary JSON file")
+	defer os.Remove(file.Name())
+	file.Write([]byte(js))
+	file.Close()
+
+	cfg := TestJson{}
+	err = FromJsonFile(file.Name(), &cfg)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, "http://example.com/", cfg.Address)
+	assert.Equal(t, 12, cfg.Sub.Level)
+}
+
+// trying to parse a missing JSON file fails
+func TestFromJsonFileNoFile(t *testing.T) {
+	cfg := TestJson{}
+	err := FromJsonFile("bogus_file_name", &cfg)
+	assert.NotNil(t, err, "Expected an error")
+}
+
+// marshal a config struct into a JSON string
+func TestToJson(t *testing.T) {
+	cfg := TestJson{
+		Address: "http://example.com/",
+		Count:   23,
+		Period:  (2 * time.Minute) + (22 * time.Second),
+		Sub:     SubNested{Enabled: true, Level: 12},
+		Tags:    []string{"red", "blue"},
+		Skipped: "hidden",
+	}
+
+	s, err := ToJson(&cfg)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, js, s)
+}
+
+// Load picks the JSON decoder for a .json file
+func TestLoadJsonFile(t *testing.T) {
+	file, err := ioutil.TempFile(".", "json_test*.json")
+	assert.Nil(t, err, "Got error trying to create temporary JSON file")
+	defer os.Remove(file.Name())
+	file.Write([]byte(js))
+	file.Close()
+
+	os.Args = []string{"test"}
+	cfg := TestJson{}
+	p, err := Trace(file.Name(), &cfg)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, "http://example.com/", cfg.Address)
+	assert.Equal(t, 12, cfg.Sub.Level)
+	assert.Equal(t, Origin{Layer: LayerFile, Name: file.Name()}, p["Sub.Level"])
+}
+
+// an explicit null clears a pointer member
+func TestFromJsonNull(t *testing.T) {
+	limit := 3
+	cfg := struct {
+		Limit *int
+		Count *int
+	}{Limit: &limit}
+	err := FromJson([]byte(`{"limit": null, "count": 4}`), &cfg)
+	assert.Nil(t, err)
+	assert.Nil(t, cfg.Limit)
+	assert.Equal(t, 4, *cfg.Count)
+}
+
+// jsonLevel decodes its own JSON, accepting a name or a number
+type jsonLevel int
+
+func (l *jsonLevel) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return json.Unmarshal(b, (*int)(l))
+	}
+	if name != "high" {
+		return fmt.Errorf("unknown level %q", name)
+	}
+	*l = 9
+	return nil
+}
+
+// jsonPair decodes its own JSON from a two item list
+type jsonPair struct {
+	A, B string
+}
+
+func (p *jsonPair) UnmarshalJSON(b []byte) error {
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	p.A, p.B = list[0], list[1]
+	return nil
+}
+
+// members implementing json.Unmarshaler decode their own part of the document
+func TestFromJsonUnmarshaler(t *testing.T) {
+	cfg := struct {
+		J    jsonLevel
+		N    jsonLevel
+		Pair *jsonPair
+	}{}
+	err := FromJson([]byte(`{"j": "high", "n": 3, "pair": ["x", "y"]}`), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, jsonLevel(9), cfg.J)
+	assert.Equal(t, jsonLevel(3), cfg.N)
+	assert.Equal(t, &jsonPair{A: "x", B: "y"}, cfg.Pair)
+
+	err = FromJson([]byte(`{"j": "x"}`), &cfg)
+	assert.Equal(t, "J", err.(*SourceError).Path)
+	assert.Equal(t, `unknown level "x"`, err.(*SourceError).Err.Error())
+}
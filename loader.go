@@ -0,0 +1,269 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Source is one layer of configuration that a Loader overlays onto a struct.
+type Source interface {
+	// Layer names the source in errors and provenance.
+	Layer() Layer
+	// Apply overlays the source's settings onto the struct pointed to by v.
+	// It records the origin of each member it sets into p, which may be nil.
+	Apply(v interface{}, p Provenance) error
+}
+
+// Loader applies an ordered list of sources to a struct, each overriding the ones before it.
+type Loader struct {
+	sources []Source
+}
+
+// NewLoader returns a Loader that applies sources in the given order.
+func NewLoader(sources ...Source) *Loader {
+	return &Loader{sources: sources}
+}
+
+// DefaultSources returns the layers used by Load, in order: struct defaults,
+// the config file (config.yml when file is empty), any .env file from WithDotEnv,
+// environment variables, and command line arguments.
+// Reorder, drop, or insert into the list and pass it to NewLoader to customize loading.
+func DefaultSources(file string, opts ...Option) []Source {
+	if file == "" {
+		file = defaultConfigFile
+	}
+	o := newOptions(opts)
+
+	sources := []Source{
+		StructDefaults(),
+		File(file),
+	}
+	if o.dotEnv != "" {
+		sources = append(sources, DotEnvFile(o.dotEnv, opts...))
+	}
+	return append(sources,
+		Environment(opts...),
+		Arguments(os.Args[1:], opts...),
+	)
+}
+
+// Load fills in the specified struct from every source, then resolves secret references in secret members with ResolveSecrets,
+// checks that every member tagged `required:"true"` was set by some source, and that all values pass their "validate:" struct tags.
+// All failures are returned together as Errors.
+func (l *Loader) Load(v interface{}) error {
+	return l.apply(v, nil)
+}
+
+// Trace fills in the specified struct like Load, and also reports which source set each member.
+func (l *Loader) Trace(v interface{}) (Provenance, error) {
+	p := Provenance{}
+	err := l.apply(v, p)
+	return p, err
+}
+
+func (l *Loader) apply(v interface{}, p Provenance) error {
+	if p == nil {
+		// required and validation checks depend on where values came from, so always keep track
+		p = Provenance{}
+	}
+
+	var errs Errors
+	prefix := ""
+	for _, src := range l.sources {
+		if env, ok := src.(envNamer); ok {
+			prefix = env.envVarPrefix()
+		}
+		errs.add(src.Layer(), "", "", src.Apply(v, p))
+	}
+	errs.add(LayerSecrets, "", "", ResolveSecrets(v))
+	errs.add("", "", "", checkRequired(v, prefix, p))
+	errs.add("", "", "", validate(v, p))
+	return errs.err()
+}
+
+// SourceFunc adapts an ordinary function such as FromYamlFile into a Source for layer.
+// Every member whose value the function changes is recorded as set by layer, so it counts for required members.
+//	secrets := config.SourceFunc("secrets", func(v interface{}) error {
+//		return config.FromYamlFile("/run/secrets/app.yml", v)
+//	})
+func SourceFunc(layer Layer, fn func(v interface{}) error) Source {
+	return funcSource{layer: layer, fn: fn}
+}
+
+type funcSource struct {
+	layer Layer
+	fn    func(v interface{}) error
+}
+
+func (s funcSource) Layer() Layer { return s.layer }
+
+func (s funcSource) Apply(v interface{}, p Provenance) error {
+	if p == nil {
+		return s.fn(v)
+	}
+
+	// the function can't report what it set, so compare the members before and after
+	before := snapshot(v)
+	err := s.fn(v)
+	for path, value := range snapshot(v) {
+		if old, ok := before[path]; !ok || !reflect.DeepEqual(old, value) {
+			p.Set(path, Origin{Layer: s.layer})
+		}
+	}
+	return err
+}
+
+// StructDefaults returns a Source that initializes struct members from "default:" struct tags.
+func StructDefaults() Source {
+	return defaultsSource{}
+}
+
+type defaultsSource struct{}
+
+func (defaultsSource) Layer() Layer                            { return LayerDefaults }
+func (defaultsSource) Apply(v interface{}, p Provenance) error { return applyDefaults(v, p) }
+
+// fileFormats maps a file format name to the function that applies a file of that format.
+var fileFormats = map[string]func(path string, v interface{}, p Provenance) error{
+	"yaml":       applyYamlFile,
+	"json":       applyJsonFile,
+	"toml":       applyTomlFile,
+	"ini":        applyIniFile,
+	"properties": applyPropertiesFile,
+}
+
+// formatExtensions maps file name extensions to the format File uses for them. Anything else is treated as YAML.
+var formatExtensions = map[string]string{
+	".json":       "json",
+	".toml":       "toml",
+	".ini":        "ini",
+	".properties": "properties",
+}
+
+// File returns a Source that extracts settings from a file, choosing the format from its extension:
+// .json is JSON, .toml is TOML, .ini is INI, .properties is a Java properties file, and anything else is YAML.
+// A missing file is skipped, but one that exists and cannot be parsed is an error.
+func File(path string) Source {
+	format, ok := formatExtensions[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		format = "yaml"
+	}
+	return fileSource{path: path, format: format}
+}
+
+// YamlFile returns a Source that extracts settings from a YAML file, whatever its extension.
+// A missing file is skipped, but one that exists and cannot be parsed is an error.
+func YamlFile(path string) Source {
+	return fileSource{path: path, format: "yaml"}
+}
+
+// TomlFile returns a Source that extracts settings from a TOML file, whatever its extension.
+// A missing file is skipped, but one that exists and cannot be parsed is an error.
+func TomlFile(path string) Source {
+	return fileSource{path: path, format: "toml"}
+}
+
+// IniFile returns a Source that extracts settings from an INI file, whatever its extension.
+// A missing file is skipped, but one that exists and cannot be parsed is an error.
+func IniFile(path string) Source {
+	return fileSource{path: path, format: "ini"}
+}
+
+// PropertiesFile returns a Source that extracts settings from a .properties file, whatever its extension.
+// A missing file is skipped, but one that exists and cannot be parsed is an error.
+func PropertiesFile(path string) Source {
+	return fileSource{path: path, format: "properties"}
+}
+
+// JsonFile returns a Source that extracts settings from a JSON file, whatever its extension.
+// A missing file is skipped, but one that exists and cannot be parsed is an error.
+func JsonFile(path string) Source {
+	return fileSource{path: path, format: "json"}
+}
+
+type fileSource struct {
+	path   string
+	format string
+}
+
+func (fileSource) Layer() Layer { return LayerFile }
+
+func (s fileSource) Apply(v interface{}, p Provenance) error {
+	err := fileFormats[s.format](s.path, v, p)
+	if err == nil || os.IsNotExist(err) {
+		return nil
+	}
+	if _, ok := err.(*SourceError); ok {
+		return err
+	}
+	return &SourceError{Layer: LayerFile, Input: s.path, Err: err}
+}
+
+// envNamer is implemented by sources that read environment variable names, so that errors for
+// missing required members can suggest the variable from the last of them, with its WithEnvPrefix prefix.
+type envNamer interface {
+	envVarPrefix() string
+}
+
+// Environment returns a Source that extracts settings from environment variables, honoring WithEnvPrefix.
+func Environment(opts ...Option) Source {
+	return environmentSource{prefix: newOptions(opts).envPrefix}
+}
+
+type environmentSource struct {
+	prefix string
+}
+
+func (environmentSource) Layer() Layer { return LayerEnvironment }
+
+func (s environmentSource) envVarPrefix() string { return s.prefix }
+
+func (s environmentSource) Apply(v interface{}, p Provenance) error {
+	return applyEnvironment(v, s.prefix, p)
+}
+
+// DotEnvFile returns a Source that extracts settings from a .env file without touching the process environment,
+// honoring WithEnvPrefix. A missing file is skipped.
+func DotEnvFile(path string, opts ...Option) Source {
+	return dotEnvSource{path: path, prefix: newOptions(opts).envPrefix}
+}
+
+type dotEnvSource struct {
+	path   string
+	prefix string
+}
+
+func (dotEnvSource) Layer() Layer { return LayerDotEnv }
+
+func (s dotEnvSource) envVarPrefix() string { return s.prefix }
+
+func (s dotEnvSource) Apply(v interface{}, p Provenance) error {
+	err := applyDotEnvFile(s.path, v, s.prefix, p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Arguments returns a Source that extracts settings from a list of key.path=value arguments,
+// honoring WithRemainingArgs.
+func Arguments(args []string, opts ...Option) Source {
+	return argumentsSource{args: args, remaining: newOptions(opts).remaining}
+}
+
+type argumentsSource struct {
+	args      []string
+	remaining *[]string
+}
+
+func (argumentsSource) Layer() Layer { return LayerArguments }
+
+func (s argumentsSource) Apply(v interface{}, p Provenance) error {
+	rest, err := applyArguments(s.args, v, p)
+	if s.remaining != nil {
+		*s.remaining = rest
+	}
+	return err
+}
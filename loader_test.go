@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sources are applied in the order given, later ones overriding earlier ones
+func TestLoaderOrder(t *testing.T) {
+	os.Setenv("ADDRESS", "http://env.com/")
+	defer os.Unsetenv("ADDRESS")
+
+	cfg := TestDefaultsNested{}
+	l := NewLoader(Arguments([]string{"Address=http://args.com/"}), Environment(), StructDefaults())
+	err := l.Load(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://env.com/", cfg.Address)
+	assert.Equal(t, (11*time.Minute)+(11*time.Second), cfg.Timeout)
+}
+
+// a custom source can be inserted between the built in ones
+func TestLoaderSourceFunc(t *testing.T) {
+	secrets := SourceFunc("secrets", func(v interface{}) error {
+		v.(*TestDefaultsNested).Sub.Level = 99
+		return nil
+	})
+
+	cfg := TestDefaultsNested{}
+	p, err := NewLoader(StructDefaults(), secrets, Arguments(nil)).Trace(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 99, cfg.Sub.Level)
+	assert.Equal(t, LayerDefaults, p["Address"].Layer)
+	assert.Equal(t, Origin{Layer: "secrets"}, p["Sub.Level"])
+	assert.Equal(t, "secrets", p["Sub.Level"].String())
+}
+
+// a required member that only a custom source sets is satisfied
+func TestLoaderSourceFuncRequired(t *testing.T) {
+	vault := SourceFunc("vault", func(v interface{}) error {
+		v.(*TestRequired).Address = "http://example.com"
+		v.(*TestRequired).Token = "t0k3n"
+		return nil
+	})
+
+	cfg := TestRequired{}
+	err := NewLoader(StructDefaults(), vault).Load(&cfg)
+	assert.Nil(t, err)
+}
+
+// failures from every source are collected, tagged with the source's layer
+func TestLoaderErrors(t *testing.T) {
+	bad := errors.New("vault sealed")
+	secrets := SourceFunc("secrets", func(v interface{}) error { return bad })
+
+	cfg := TestArguments{}
+	err := NewLoader(secrets, YamlFile("bogus_file_name.yml"), Arguments([]string{"Timeout=abc"})).Load(&cfg)
+	errs, ok := err.(Errors)
+	assert.True(t, ok, "Expected Errors")
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, Layer("secrets"), errs[0].(*SourceError).Layer)
+	assert.True(t, errors.Is(errs[0], bad))
+	assert.Equal(t, LayerArguments, errs[1].(*SourceError).Layer)
+}
+
+func TestDefaultSources(t *testing.T) {
+	os.Args = []string{"test"}
+
+	sources := DefaultSources("")
+	assert.Equal(t, 4, len(sources))
+	assert.Equal(t, LayerDefaults, sources[0].Layer())
+	assert.Equal(t, LayerFile, sources[1].Layer())
+	assert.Equal(t, fileSource{path: defaultConfigFile, format: "yaml"}, sources[1])
+	assert.Equal(t, LayerEnvironment, sources[2].Layer())
+	assert.Equal(t, LayerArguments, sources[3].Layer())
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"time"
+)
+
+// Option customizes the layers that Load and DefaultSources use, and how Watch follows the config file.
+type Option func(*options)
+
+type options struct {
+	dotEnv       string
+	envPrefix    string
+	remaining    *[]string
+	pollInterval time.Duration
+}
+
+// WithDotEnv adds a .env file layer between the config file and the environment variables.
+// A missing file is skipped.
+func WithDotEnv(path string) Option {
+	return func(o *options) {
+		o.dotEnv = path
+	}
+}
+
+// WithEnvPrefix makes environment variable and .env names start with prefix and an underscore,
+// so with prefix MYAPP the member Sub.Level is read from MYAPP_SUB_LEVEL.
+// Members with an `env:"NAME"` tag are still read from exactly NAME.
+func WithEnvPrefix(prefix string) Option {
+	return func(o *options) {
+		o.envPrefix = prefix
+	}
+}
+
+// WithRemainingArgs makes the command line arguments layer store the positional arguments
+// that no struct member took into *args, in their original order, as ParseArguments returns them.
+func WithRemainingArgs(args *[]string) Option {
+	return func(o *options) {
+		o.remaining = args
+	}
+}
+
+// WithPolling makes Watch check the config file every interval instead of relying on file system notifications,
+// which some network and container file systems don't deliver.
+func WithPolling(interval time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
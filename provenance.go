@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Origin describes where a struct member's value came from.
+type Origin struct {
+	// Layer is the configuration source that set the value
+	Layer Layer
+	// Name is the default tag value, file path, environment variable name, or argument text
+	Name string
+	// Line is the line within a file that set the value, or 0 if it is unknown
+	Line int
+	// Index is the position of the argument within the parsed argument list
+	Index int
+}
+
+// String describes the origin in a form suitable for logs.
+//
+//	default "77"
+//	file config.yml:3
+//	dotenv .env:2
+//	env SUB_LEVEL
+//	args[2] sub.level=42
+func (o Origin) String() string {
+	switch o.Layer {
+	case LayerDefaults:
+		return fmt.Sprintf("default %q", o.Name)
+	case LayerFile, LayerDotEnv:
+		if o.Line > 0 {
+			return fmt.Sprintf("%s %s:%d", o.Layer, o.Name, o.Line)
+		}
+		return fmt.Sprintf("%s %s", o.Layer, o.Name)
+	case LayerEnvironment:
+		return "env " + o.Name
+	case LayerArguments:
+		return fmt.Sprintf("args[%d] %s", o.Index, o.Name)
+	}
+	if o.Name == "" {
+		return string(o.Layer)
+	}
+	return fmt.Sprintf("%s %s", o.Layer, o.Name)
+}
+
+// Provenance maps struct member paths such as "Sub.Level" to the origin of their final value.
+// Members that no source touched are absent.
+type Provenance map[string]Origin
+
+// Set records that the member at path was set by origin o, replacing any earlier origin.
+// It does nothing on a nil Provenance, so sources can record unconditionally.
+func (p Provenance) Set(path string, o Origin) {
+	if p != nil {
+		p[path] = o
+	}
+}
+
+// setValue records o for the member at path, which was set from the text s, and also for every member
+// that s sets beneath it when it is a struct value such as "host=a;port=80".
+func (p Provenance) setValue(path string, o Origin, s string, typ reflect.Type) {
+	p.Set(path, o)
+	for _, sub := range structMembers(s, typ) {
+		p.Set(path+"."+sub, o)
+	}
+}
+
+// Trace fills in the specified struct exactly like LoadE, and also reports which source set each member.
+func Trace(file string, v interface{}, opts ...Option) (Provenance, error) {
+	p := Provenance{}
+	err := load(file, v, p, opts)
+	return p, err
+}
+
+// snapshot captures the current value of every leaf member of v, keyed by path.
+func snapshot(v interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	walkFields(v, func(f field) error {
+		values[f.Path()] = copyValue(f.value)
+		return nil
+	})
+	return values
+}
+
+// copyValue returns an independent copy of rv's top level contents, so later in-place edits to slices and maps still show up as changes.
+func copyValue(rv reflect.Value) interface{} {
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			break
+		}
+		c := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		reflect.Copy(c, rv)
+		return c.Interface()
+	case reflect.Map:
+		if rv.IsNil() {
+			break
+		}
+		c := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return c.Interface()
+	}
+	return rv.Interface()
+}
+
+// traceYaml records every member that the YAML document sets, along with the line that set it.
+func traceYaml(yml []byte, file string, v interface{}, p Provenance) {
+	var doc yaml3.Node
+	if yaml3.Unmarshal(yml, &doc) != nil || len(doc.Content) == 0 {
+		return
+	}
+	traceNode(doc.Content[0], reflect.TypeOf(v), nil, file, p)
+}
+
+func traceNode(node *yaml3.Node, typ reflect.Type, path []string, file string, p Provenance) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if node.Kind == yaml3.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml3.MappingNode || typ.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		index, names, ok := yamlField(typ, key.Value)
+		if !ok || value.Tag == "!!null" {
+			continue
+		}
+
+		fp := append(append([]string{}, path...), names...)
+		if ft := typ.FieldByIndex(index).Type; isNestedType(indirectType(ft)) && !yamlUnmarshals(ft) {
+			traceNode(value, ft, fp, file, p)
+			continue
+		}
+		p.Set(joinPath(fp), Origin{Layer: LayerFile, Name: file, Line: key.Line})
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ymlNested = `---
+address: http://example.com/
+sub:
+  enabled: false
+`
+
+// each member reports the last layer that set it
+func TestTrace(t *testing.T) {
+	file, err := ioutil.TempFile(".", "yaml_test")
+	assert.Nil(t, err, "Got error trying to create temporary YAML file")
+	defer os.Remove(file.Name())
+	file.Write([]byte(ymlNested))
+	file.Close()
+
+	os.Setenv("TIMEOUT", "1m")
+	defer os.Unsetenv("TIMEOUT")
+	os.Args = []string{"test", "Address=http://example.com/app", "sub.level=42"}
+
+	cfg := TestDefaultsNested{}
+	p, err := Trace(file.Name(), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 42, cfg.Sub.Level)
+
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "Address=http://example.com/app", Index: 0}, p["Address"])
+	assert.Equal(t, Origin{Layer: LayerEnvironment, Name: "TIMEOUT"}, p["Timeout"])
+	assert.Equal(t, Origin{Layer: LayerFile, Name: file.Name(), Line: 4}, p["Sub.Enabled"])
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "sub.level=42", Index: 1}, p["Sub.Level"])
+	assert.Equal(t, 4, len(p))
+}
+
+// members left at their zero value by every layer have no origin
+func TestTraceUntouched(t *testing.T) {
+	os.Args = []string{"test"}
+
+	cfg := TestDefaultsNested{Address: "http://preset.com/"}
+	p, err := Trace("bogus_file_name.yml", &cfg)
+	assert.Nil(t, err)
+	_, ok := p["Address"]
+	assert.False(t, ok, "Expected no origin for a preset member")
+	assert.Equal(t, Origin{Layer: LayerDefaults, Name: "77"}, p["Sub.Level"])
+}
+
+func TestOriginString(t *testing.T) {
+	assert.Equal(t, `default "77"`, Origin{Layer: LayerDefaults, Name: "77"}.String())
+	assert.Equal(t, "file config.yml:3", Origin{Layer: LayerFile, Name: "config.yml", Line: 3}.String())
+	assert.Equal(t, "file config.yml", Origin{Layer: LayerFile, Name: "config.yml"}.String())
+	assert.Equal(t, "env SUB_LEVEL", Origin{Layer: LayerEnvironment, Name: "SUB_LEVEL"}.String())
+	assert.Equal(t, "args[2] sub.level=42", Origin{Layer: LayerArguments, Name: "sub.level=42", Index: 2}.String())
+}
+
+// recording into a nil Provenance is harmless
+func TestProvenanceSetNil(t *testing.T) {
+	var p Provenance
+	p.Set("Address", Origin{Layer: LayerDefaults})
+	assert.Equal(t, 0, len(p))
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// redacted replaces secret values wherever configuration is printed
+const redacted = "******"
+
+// Secret is a string that loads normally but prints as ****** through fmt, ToYaml, ToJson, and ToToml.
+// Convert it with string(s) to use the real value.
+type Secret string
+
+// String returns the mask rather than the secret.
+func (s Secret) String() string {
+	return redacted
+}
+
+// GoString returns the quoted mask for %#v.
+func (s Secret) GoString() string {
+	return `"` + redacted + `"`
+}
+
+// MarshalYAML writes the mask rather than the secret.
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return redacted, nil
+}
+
+// MarshalJSON writes the mask rather than the secret.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redacted)
+}
+
+// isSecret reports whether a struct member is tagged `secret:"true"` or has the Secret type.
+func isSecret(sf reflect.StructField) bool {
+	return sf.Tag.Get("secret") == "true" || sf.Type == reflect.TypeOf(Secret(""))
+}
+
+// Redacted returns a pointer to a copy of the struct pointed to by v, with every member tagged
+// `secret:"true"` or of type Secret masked, including those of nested structs it points to
+// and of structs held in slices, arrays, maps, and interfaces.
+// Strings become ******, and other kinds become their zero value.
+// fmt only masks the Secret type by itself, so use Redacted to print a configuration
+// with fmt without revealing members tagged `secret:"true"`.
+//	fmt.Printf("%+v\n", config.Redacted(&cfg))
+func Redacted(v interface{}) interface{} {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return v
+	}
+
+	c := reflect.New(rv.Type())
+	c.Elem().Set(redactValue(rv))
+	return c.Interface()
+}
+
+// redactValue returns rv with its secret members masked. The structs, pointers, slices, arrays, maps,
+// and interfaces leading to a secret are copied so that rv is left alone, and everything else is shared with it.
+func redactValue(rv reflect.Value) reflect.Value {
+	if !holdsSecrets(rv.Type(), map[reflect.Type]bool{}) {
+		return rv
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		c := reflect.New(rv.Type()).Elem()
+		c.Set(rv)
+		for i := 0; i < rv.NumField(); i++ {
+			sf := rv.Type().Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			if _, ok := nested(rv.Field(i)); isSecret(sf) && !ok {
+				mask(c.Field(i))
+				continue
+			}
+			c.Field(i).Set(redactValue(rv.Field(i)))
+		}
+		return c
+
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return rv
+		}
+		c := reflect.New(rv.Type().Elem())
+		c.Elem().Set(redactValue(rv.Elem()))
+		return c
+
+	case reflect.Interface:
+		if rv.IsNil() {
+			return rv
+		}
+		c := reflect.New(rv.Type()).Elem()
+		c.Set(redactValue(rv.Elem()))
+		return c
+
+	case reflect.Slice:
+		if rv.IsNil() {
+			return rv
+		}
+		c := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			c.Index(i).Set(redactValue(rv.Index(i)))
+		}
+		return c
+
+	case reflect.Array:
+		c := reflect.New(rv.Type()).Elem()
+		for i := 0; i < rv.Len(); i++ {
+			c.Index(i).Set(redactValue(rv.Index(i)))
+		}
+		return c
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return rv
+		}
+		c := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), redactValue(iter.Value()))
+		}
+		return c
+	}
+	return rv
+}
+
+// holdsSecrets reports whether values of typ may hold a secret member, directly or through
+// pointers, slices, arrays, and maps. Interfaces may hold anything, so their contents are checked as they are copied.
+func holdsSecrets(typ reflect.Type, seen map[reflect.Type]bool) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return holdsSecrets(typ.Elem(), seen)
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		if !isNestedType(typ) || seen[typ] {
+			return false
+		}
+		seen[typ] = true
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			if sf.PkgPath == "" && (isSecret(sf) || holdsSecrets(sf.Type, seen)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mask replaces a secret value with ****** if it is a string, or else with its zero value.
+func mask(rv reflect.Value) {
+	if rv.Kind() == reflect.String {
+		rv.SetString(redacted)
+	} else {
+		rv.Set(reflect.Zero(rv.Type()))
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestRedact struct {
+	User     string `yaml:"user"`
+	Password string `yaml:"password" secret:"true"`
+	Token    Secret `yaml:"token"`
+	Pin      int    `yaml:"pin" secret:"true"`
+	Keys     []Secret
+}
+
+func redactConfig() TestRedact {
+	return TestRedact{User: "admin", Password: "hunter2", Token: "t0k3n", Pin: 1234, Keys: []Secret{"k1"}}
+}
+
+func TestSecretFormat(t *testing.T) {
+	s := Secret("t0k3n")
+	assert.Equal(t, "******", fmt.Sprint(s))
+	assert.Equal(t, "******", fmt.Sprintf("%s", s))
+	assert.Equal(t, `"******"`, fmt.Sprintf("%#v", s))
+	assert.Equal(t, "t0k3n", string(s))
+
+	// fmt can't see the secret tag on a plain string, so print the struct through Redacted
+	cfg := redactConfig()
+	assert.NotContains(t, fmt.Sprintf("%+v", cfg), "t0k3n")
+	assert.NotContains(t, fmt.Sprintf("%+v", Redacted(&cfg)), "hunter2")
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := redactConfig()
+	r := Redacted(&cfg).(*TestRedact)
+	assert.Equal(t, "admin", r.User)
+	assert.Equal(t, "******", r.Password)
+	assert.Equal(t, Secret("******"), r.Token)
+	assert.Equal(t, 0, r.Pin)
+	assert.Equal(t, "hunter2", cfg.Password, "Expected the original to be left alone")
+	assert.Equal(t, "{User:admin Password:****** Token:****** Pin:0 Keys:[******]}", fmt.Sprintf("%+v", *r))
+}
+
+func TestToYamlRedacts(t *testing.T) {
+	cfg := redactConfig()
+	s, err := ToYaml(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "---\nuser: admin\npassword: '******'\ntoken: '******'\npin: 0\nkeys:\n- '******'\n", s)
+}
+
+func TestToJsonRedacts(t *testing.T) {
+	cfg := redactConfig()
+	s, err := ToJson(cfg)
+	assert.Nil(t, err)
+	assert.NotContains(t, s, "hunter2")
+	assert.NotContains(t, s, "t0k3n")
+	assert.NotContains(t, s, "k1")
+	assert.Contains(t, s, `"password": "******"`)
+}
+
+func TestToTomlRedacts(t *testing.T) {
+	cfg := redactConfig()
+	s, err := ToToml(&cfg)
+	assert.Nil(t, err)
+	assert.NotContains(t, s, "hunter2")
+	assert.NotContains(t, s, "t0k3n")
+	assert.NotContains(t, s, "k1")
+}
+
+// secrets still load their real values
+func TestLoadSecret(t *testing.T) {
+	os.Setenv("TOKEN", "t0k3n")
+	defer os.Unsetenv("TOKEN")
+	os.Args = []string{"test", "password=hunter2"}
+
+	cfg := TestRedact{}
+	err := LoadE("bogus_file_name.yml", &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, Secret("t0k3n"), cfg.Token)
+	assert.Equal(t, "hunter2", cfg.Password)
+}
+
+// secrets in pointed to nested structs are masked in the copy only
+func TestRedactedPointer(t *testing.T) {
+	type sub struct {
+		Password string `yaml:"password" secret:"true"`
+	}
+	cfg := struct {
+		Sub  *sub `yaml:"sub"`
+		None *sub `yaml:"none"`
+	}{Sub: &sub{Password: "hunter2"}}
+
+	s, err := ToYaml(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "---\nsub:\n  password: '******'\nnone: null\n", s)
+	assert.Equal(t, "hunter2", cfg.Sub.Password, "Expected the original to be left alone")
+
+	s, err = ToJson(&cfg)
+	assert.Nil(t, err)
+	assert.NotContains(t, s, "hunter2")
+}
+
+type TestRedactUsers struct {
+	Users  []TestRedact
+	Admins map[string]TestRedact
+	Owner  [1]*TestRedact
+	Extra  interface{}
+}
+
+// secrets are masked in structs held by slices, maps, arrays, and interfaces, without touching the original
+func TestRedactedCollections(t *testing.T) {
+	cfg := TestRedactUsers{
+		Users:  []TestRedact{redactConfig()},
+		Admins: map[string]TestRedact{"root": redactConfig()},
+		Owner:  [1]*TestRedact{{Password: "owner-pw"}},
+		Extra:  []interface{}{redactConfig()},
+	}
+
+	r := Redacted(&cfg).(*TestRedactUsers)
+	assert.Equal(t, "admin", r.Users[0].User)
+	assert.Equal(t, "******", r.Users[0].Password)
+	assert.Equal(t, 0, r.Users[0].Pin)
+	assert.Equal(t, "******", r.Admins["root"].Password)
+	assert.Equal(t, "******", r.Owner[0].Password)
+	assert.Equal(t, "******", r.Extra.([]interface{})[0].(TestRedact).Password)
+
+	assert.Equal(t, "hunter2", cfg.Users[0].Password)
+	assert.Equal(t, "hunter2", cfg.Admins["root"].Password)
+	assert.Equal(t, "owner-pw", cfg.Owner[0].Password)
+	assert.Equal(t, "hunter2", cfg.Extra.([]interface{})[0].(TestRedact).Password)
+
+	for _, to := range []func(interface{}) (string, error){ToYaml, ToJson, ToToml} {
+		out, err := to(&cfg)
+		assert.Nil(t, err)
+		assert.NotContains(t, out, "hunter2")
+		assert.NotContains(t, out, "owner-pw")
+		assert.Contains(t, out, "admin")
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RequiredError describes a required struct member that no configuration source set,
+// along with the names that would have set it.
+type RequiredError struct {
+	Path string
+	// Key is the period separated key path in a config file
+	Key string
+	// Env is the environment variable name
+	Env string
+	// Arg is the command line argument name
+	Arg string
+}
+
+// Error explains every way the member could have been set.
+func (e *RequiredError) Error() string {
+	return fmt.Sprintf("%s is required: set %s in the config file, %s in the environment, or %s=VALUE on the command line",
+		e.Path, e.Key, e.Env, e.Arg)
+}
+
+// isRequired reports whether a member is tagged `required:"true"` or `config:",required"`.
+func isRequired(f field) bool {
+	if f.sf.Tag.Get("required") == "true" {
+		return true
+	}
+	for _, opt := range strings.Split(f.sf.Tag.Get("config"), ",")[1:] {
+		if opt == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRequired returns a *RequiredError for every required member of v without an origin in p.
+// Unlike the validate tag's required rule, a value the struct already held before loading doesn't count.
+// Members of subcommand sections that weren't chosen on the command line are skipped.
+// The environment variable suggested in each error starts with prefix.
+func checkRequired(v interface{}, prefix string, p Provenance) error {
+	var errs Errors
+	walkFields(v, func(f field) error {
+		if _, ok := p[f.Path()]; ok || !isRequired(f) || !commandChosen(f, p) {
+			return nil
+		}
+		key := f.Key("yaml")
+		errs = append(errs, &RequiredError{
+			Path: f.Path(),
+			Key:  key,
+			Env:  fieldEnvNames(f, prefix)[0],
+			Arg:  key,
+		})
+		return nil
+	})
+	return errs.err()
+}
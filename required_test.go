@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestRequired struct {
+	Address string `yaml:"addr" required:"true"`
+	Token   string `config:",required"`
+	Sub     struct {
+		LogLevel int `yaml:"log_level" required:"true" default:"3"`
+		Name     string
+	} `yaml:"sub"`
+}
+
+// required members that no source set are reported with every way to set them
+func TestLoadRequired(t *testing.T) {
+	os.Args = []string{"test"}
+
+	cfg := TestRequired{Token: "preset"}
+	err := LoadE("bogus_file_name.yml", &cfg)
+	errs, ok := err.(Errors)
+	assert.True(t, ok, "Expected Errors")
+	assert.Equal(t, []error{
+		&RequiredError{Path: "Address", Key: "addr", Env: "ADDRESS", Arg: "addr"},
+		&RequiredError{Path: "Token", Key: "token", Env: "TOKEN", Arg: "token"},
+	}, []error(errs))
+	assert.Equal(t, "Address is required: set addr in the config file, ADDRESS in the environment, or addr=VALUE on the command line", errs[0].Error())
+}
+
+func TestLoadRequiredSatisfied(t *testing.T) {
+	os.Setenv("TOKEN", "secret")
+	defer os.Unsetenv("TOKEN")
+	os.Args = []string{"test", "addr=http://example.com"}
+
+	cfg := TestRequired{}
+	err := LoadE("bogus_file_name.yml", &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, cfg.Sub.LogLevel)
+}
+
+func TestFieldKey(t *testing.T) {
+	var keys []string
+	walkFields(&TestRequired{}, func(f field) error {
+		keys = append(keys, f.Key("yaml"))
+		return nil
+	})
+	assert.Equal(t, []string{"addr", "token", "sub.log_level", "sub.name"}, keys)
+}
+
+// required members of a pointed to nested struct are checked, and setting them through the pointer counts
+func TestLoadRequiredPointer(t *testing.T) {
+	type sub struct {
+		Level int `yaml:"level" required:"true"`
+	}
+	type config struct {
+		Sub *sub `yaml:"sub"`
+	}
+
+	os.Args = []string{"test"}
+	cfg := config{Sub: &sub{}}
+	err := LoadE("bogus_file_name.yml", &cfg)
+	assert.Equal(t, Errors{&RequiredError{Path: "Sub.Level", Key: "sub.level", Env: "SUB_LEVEL", Arg: "sub.level"}}, err)
+
+	os.Args = []string{"test", "sub.level=3"}
+	cfg = config{}
+	err = LoadE("bogus_file_name.yml", &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, cfg.Sub.Level)
+}
+
+// the suggested environment variable takes its prefix from the environment source
+func TestLoadRequiredPrefix(t *testing.T) {
+	os.Args = []string{"test"}
+
+	cfg := TestRequired{Token: "preset"}
+	err := LoadE("bogus_file_name.yml", &cfg, WithEnvPrefix("myapp"))
+	assert.Equal(t, "MYAPP_ADDRESS", err.(Errors)[0].(*RequiredError).Env)
+
+	cfg = TestRequired{Token: "preset"}
+	err = NewLoader(Environment(WithEnvPrefix("myapp"))).Load(&cfg)
+	assert.Equal(t, "MYAPP_ADDRESS", err.(Errors)[0].(*RequiredError).Env)
+}
+
+type TestRequiredInline struct {
+	Server struct {
+		Host string `required:"true"`
+		Port int    `required:"true"`
+		Sub  struct {
+			Level int `required:"true"`
+		}
+	}
+	Backup *struct {
+		Host string `required:"true"`
+	}
+}
+
+// members set inside a struct value written in one argument or variable count as set, and the others still don't
+func TestLoadRequiredInline(t *testing.T) {
+	c := TestRequiredInline{}
+	p, err := NewLoader(Arguments([]string{"server=host=a;sub={level: 2}"})).Trace(&c)
+	assert.Equal(t, "a", c.Server.Host)
+	assert.Equal(t, 2, c.Server.Sub.Level)
+	errs := err.(Errors)
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "Server.Port", errs[0].(*RequiredError).Path)
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "server=host=a;sub={level: 2}"}, p["Server.Host"])
+	assert.Equal(t, LayerArguments, p["Server.Sub.Level"].Layer)
+
+	os.Setenv("BACKUP", "host=b")
+	defer os.Unsetenv("BACKUP")
+	c = TestRequiredInline{}
+	err = NewLoader(Environment(), Arguments([]string{"server=host=a;port=80;sub.level=1"})).Load(&c)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", c.Backup.Host)
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// LayerSecrets is the secret resolution pass that runs after every other layer
+const LayerSecrets Layer = "secrets"
+
+// defaultSecretsDir is where ${secret:name} references are looked up, following the Docker and Kubernetes convention
+const defaultSecretsDir = "/run/secrets"
+
+// ErrSecretNotFound indicates a secret reference that its resolver couldn't find
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretResolver looks up the value of a secret by name.
+type SecretResolver interface {
+	Resolve(name string) (string, error)
+}
+
+// SecretResolverFunc adapts an ordinary function into a SecretResolver.
+type SecretResolverFunc func(name string) (string, error)
+
+// Resolve calls f(name).
+func (f SecretResolverFunc) Resolve(name string) (string, error) {
+	return f(name)
+}
+
+// FileSecretResolver reads a secret from a file, dropping a trailing newline.
+// Relative names are found in Dir when it is set.
+type FileSecretResolver struct {
+	Dir string
+}
+
+// Resolve returns the contents of the file named by name.
+func (r FileSecretResolver) Resolve(name string) (string, error) {
+	if r.Dir != "" && !filepath.IsAbs(name) {
+		name = filepath.Join(r.Dir, name)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// envSecretResolver reads a secret from another environment variable.
+func envSecretResolver(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+var (
+	secretResolversMu sync.RWMutex
+	secretResolvers   = map[string]SecretResolver{
+		"file":   FileSecretResolver{},
+		"env":    SecretResolverFunc(envSecretResolver),
+		"secret": FileSecretResolver{Dir: defaultSecretsDir},
+	}
+)
+
+// RegisterSecretResolver makes r resolve references using scheme, replacing any earlier resolver for it.
+// The file, env, and secret schemes are built in.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+	secretResolvers[scheme] = r
+}
+
+func secretResolver(scheme string) (SecretResolver, bool) {
+	secretResolversMu.RLock()
+	defer secretResolversMu.RUnlock()
+	r, ok := secretResolvers[scheme]
+	return r, ok
+}
+
+// ResolveSecrets replaces secret references in the members of the struct pointed to by v that are tagged
+// `secret:"true"` or have the Secret type. Other members are never touched, so a plain URL stays as written.
+// A whole value of the form scheme://name, or any ${scheme:name} inside a value, is replaced
+// by what the resolver registered for scheme returns. Values using unregistered schemes are left alone.
+//	file:///run/secrets/db_pass    contents of the file
+//	env://OTHER_VAR                value of another environment variable
+//	${secret:db_pass}              contents of /run/secrets/db_pass
+// Load, LoadE, and every Loader run it automatically after all layers are applied.
+func ResolveSecrets(v interface{}) error {
+	var errs Errors
+	err := walkFields(v, func(f field) error {
+		if !isSecret(f.sf) || f.value.Kind() != reflect.String || !f.value.CanSet() {
+			return nil
+		}
+		s := f.value.String()
+		resolved, err := resolveSecret(s)
+		if err != nil {
+			errs.add(LayerSecrets, f.Path(), s, err)
+			return nil
+		}
+		if resolved != s {
+			f.value.SetString(resolved)
+		}
+		return nil
+	})
+	errs.add(LayerSecrets, "", "", err)
+	return errs.err()
+}
+
+// resolveSecret expands every secret reference in s.
+func resolveSecret(s string) (string, error) {
+	if i := strings.Index(s, "://"); i > 0 {
+		if r, ok := secretResolver(s[:i]); ok {
+			return r.Resolve(s[i+3:])
+		}
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start
+
+		// a reference without a scheme, or with one that isn't registered, is left as it is
+		colon := strings.Index(s[start:end], ":")
+		var r SecretResolver
+		ok := false
+		if colon >= 0 {
+			colon += start
+			r, ok = secretResolver(s[start+2 : colon])
+		}
+		if !ok {
+			b.WriteString(s[:end+1])
+			s = s[end+1:]
+			continue
+		}
+		value, err := r.Resolve(s[colon+1 : end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:start])
+		b.WriteString(value)
+		s = s[end+1:]
+	}
+	b.WriteString(s)
+	return b.String(), nil
+}
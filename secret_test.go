@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestSecrets struct {
+	Password string `secret:"true"`
+	DSN      string `secret:"true"`
+	Token    Secret
+	Address  string
+	Data     string
+	Count    int
+}
+
+func TestResolveSecrets(t *testing.T) {
+	file, err := ioutil.TempFile(".", "secret_test")
+	assert.Nil(t, err, "Got error trying to create temporary secret file")
+	defer os.Remove(file.Name())
+	file.Write([]byte("hunter2\n"))
+	file.Close()
+	abs, _ := filepath.Abs(file.Name())
+
+	os.Setenv("SECRET_TEST_TOKEN", "t0k3n")
+	defer os.Unsetenv("SECRET_TEST_TOKEN")
+	RegisterSecretResolver("vault", SecretResolverFunc(func(name string) (string, error) {
+		return "from-vault-" + name, nil
+	}))
+	defer func() {
+		secretResolversMu.Lock()
+		delete(secretResolvers, "vault")
+		secretResolversMu.Unlock()
+	}()
+
+	cfg := TestSecrets{
+		Password: "file://" + abs,
+		DSN:      "postgres://app:${vault:db}@db:5432/app?x=${nope:y}",
+		Token:    "env://SECRET_TEST_TOKEN",
+		Address:  "http://example.com/",
+	}
+	err = ResolveSecrets(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "hunter2", cfg.Password)
+	assert.Equal(t, "postgres://app:from-vault-db@db:5432/app?x=${nope:y}", cfg.DSN)
+	assert.Equal(t, Secret("t0k3n"), cfg.Token)
+	assert.Equal(t, "http://example.com/", cfg.Address)
+
+	// references that aren't secrets are skipped over, wherever they appear
+	for in, out := range map[string]string{
+		"${HOME}-${vault:db}":      "${HOME}-from-vault-db",
+		"${nope:y}${vault:a}":      "${nope:y}from-vault-a",
+		"${vault:a}:${vault:b}${x": "from-vault-a:from-vault-b${x",
+	} {
+		resolved, err := resolveSecret(in)
+		assert.Nil(t, err)
+		assert.Equal(t, out, resolved, in)
+	}
+}
+
+// members that aren't secrets keep values that look like references
+func TestResolveSecretsUntagged(t *testing.T) {
+	cfg := TestSecrets{
+		Address: "file:///etc/hostname",
+		Data:    "prefix-${secret:db_pass}",
+	}
+	err := ResolveSecrets(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "file:///etc/hostname", cfg.Address)
+	assert.Equal(t, "prefix-${secret:db_pass}", cfg.Data)
+}
+
+func TestResolveSecretsMissing(t *testing.T) {
+	cfg := TestSecrets{Token: "env://SECRET_TEST_MISSING", Password: "file:///no/such/secret"}
+	err := ResolveSecrets(&cfg)
+	errs := err.(Errors)
+	assert.Equal(t, 2, len(errs))
+	serr := errs[0].(*SourceError)
+	assert.Equal(t, LayerSecrets, serr.Layer)
+	assert.Equal(t, "Password", serr.Path)
+	assert.Equal(t, "file:///no/such/secret", serr.Input)
+	assert.True(t, errors.Is(errs[1], ErrSecretNotFound))
+	assert.Equal(t, Secret("env://SECRET_TEST_MISSING"), cfg.Token)
+}
+
+func TestFileSecretResolverDir(t *testing.T) {
+	dir, err := ioutil.TempDir(".", "secrets_test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "db_pass"), []byte("s3cr3t\r\n"), 0600)
+
+	value, err := FileSecretResolver{Dir: dir}.Resolve("db_pass")
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", value)
+}
+
+// Load resolves references supplied by any layer
+func TestLoadResolvesSecrets(t *testing.T) {
+	os.Setenv("SECRET_TEST_TOKEN", "t0k3n")
+	defer os.Unsetenv("SECRET_TEST_TOKEN")
+	os.Args = []string{"test", "token=env://SECRET_TEST_TOKEN", "data=file:///etc/hostname"}
+
+	cfg := TestSecrets{}
+	err := LoadE("bogus_file_name.yml", &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, Secret("t0k3n"), cfg.Token)
+	assert.Equal(t, "file:///etc/hostname", cfg.Data)
+}
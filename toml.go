@@ -0,0 +1,83 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlTags are the struct tags consulted for TOML key names, falling back to the yaml names so one struct serves both.
+var tomlTags = []string{"toml", "yaml"}
+
+// FromToml extracts settings from a TOML string.
+// Tables map onto nested structs, and keys match the toml tag name, then the yaml tag name, then the Go field name, ignoring case.
+func FromToml(tml []byte, v interface{}) error {
+	tree, err := parseToml(tml)
+	if err != nil {
+		return err
+	}
+	return decodeTree(tree, reflect.ValueOf(v), tomlTags)
+}
+
+// FromTomlFile extracts settings from a TOML file.
+func FromTomlFile(path string, v interface{}) error {
+	return applyTomlFile(path, v, nil)
+}
+
+func applyTomlFile(path string, v interface{}, p Provenance) error {
+	tml, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = FromToml(tml, v)
+	if err == nil && p != nil {
+		tree, _ := parseToml(tml)
+		traceTree(tree, reflect.TypeOf(v), tomlTags, nil, path, p)
+	}
+	return err
+}
+
+// parseToml decodes a TOML document into a generic tree.
+func parseToml(tml []byte) (interface{}, error) {
+	tree := map[string]interface{}{}
+	_, err := toml.Decode(string(tml), &tree)
+	return tree, err
+}
+
+// ToToml marshals the struct into a TOML string, naming keys the same way FromToml matches them.
+// Members tagged `secret:"true"` or of type Secret are written as ******.
+func ToToml(v interface{}) (string, error) {
+	var buff bytes.Buffer
+	err := toml.NewEncoder(&buff).Encode(tomlTree(encodeTree(reflect.ValueOf(Redacted(v)), tomlTags)))
+	if err == nil {
+		return buff.String(), nil
+	}
+
+	return "", err
+}
+
+// tomlTree converts ordered tables into plain maps for the TOML encoder, dropping nil values which TOML cannot express.
+func tomlTree(node interface{}) interface{} {
+	switch n := node.(type) {
+	case treeMap:
+		m := make(map[string]interface{}, len(n))
+		for _, item := range n {
+			if item.Value != nil {
+				m[item.Key] = tomlTree(item.Value)
+			}
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, 0, len(n))
+		for _, item := range n {
+			if item != nil {
+				list = append(list, tomlTree(item))
+			}
+		}
+		return list
+	}
+	return node
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestToml struct {
+	Address string        `yaml:"address"`
+	Count   int           `toml:"total" yaml:"count"`
+	Period  time.Duration `yaml:"period"`
+	HTTP    SubNested
+	Tags    []string `yaml:"tags"`
+}
+
+var tml = `address = "http://example.com/"
+period = "2m22s"
+tags = ["red", "blue"]
+total = 23
+
+[http]
+  enabled = true
+  level = 12
+`
+
+// parsing a good TOML string succeeds, with tables mapping onto nested structs
+func TestFromToml(t *testing.T) {
+	cfg := TestToml{}
+	err := FromToml([]byte(tml), &cfg)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, "http://example.com/", cfg.Address)
+	assert.Equal(t, 23, cfg.Count)
+	assert.Equal(t, (2*time.Minute)+(22*time.Second), cfg.Period)
+	assert.Equal(t, true, cfg.HTTP.Enabled)
+	assert.Equal(t, 12, cfg.HTTP.Level)
+	assert.Equal(t, []string{"red", "blue"}, cfg.Tags)
+}
+
+// parsing a bad TOML string fails
+func TestFromTomlBad(t *testing.T) {
+	cfg := TestToml{}
+	err := FromToml([]byte(notYml), &cfg)
+	assert.NotNil(t, err, "Expected an error because of bad TOML")
+	assert.Equal(t, "", cfg.Address)
+}
+
+// parsing a good TOML file succeeds
+func TestFromTomlFile(t *testing.T) {
+	file, err := ioutil.TempFile(".", "toml_test")
+	assert.Nil(t, err, "Got error trying to create temporary TOML file")
+	defer os.Remove(file.Name())
+	file.Write([]byte(tml))
+	file.Close()
+
+	cfg := TestToml{}
+	err = FromTomlFile(file.Name(), &cfg)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, 12, cfg.HTTP.Level)
+}
+
+// trying to parse a missing TOML file fails
+func TestFromTomlFileNoFile(t *testing.T) {
+	cfg := TestToml{}
+	err := FromTomlFile("bogus_file_name", &cfg)
+	assert.NotNil(t, err, "Expected an error")
+}
+
+// marshal a config struct into a TOML string
+func TestToToml(t *testing.T) {
+	cfg := TestToml{
+		Address: "http://example.com/",
+		Count:   23,
+		Period:  (2 * time.Minute) + (22 * time.Second),
+		HTTP:    SubNested{Enabled: true, Level: 12},
+		Tags:    []string{"red", "blue"},
+	}
+
+	s, err := ToToml(&cfg)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, tml, s)
+
+	back := TestToml{}
+	err = FromToml([]byte(s), &back)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, cfg, back)
+}
+
+// Load picks the TOML decoder for a .toml file
+func TestLoadTomlFile(t *testing.T) {
+	file, err := ioutil.TempFile(".", "toml_test*.toml")
+	assert.Nil(t, err, "Got error trying to create temporary TOML file")
+	defer os.Remove(file.Name())
+	file.Write([]byte(tml))
+	file.Close()
+
+	os.Args = []string{"test"}
+	cfg := TestToml{}
+	err = LoadE(file.Name(), &cfg)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, 23, cfg.Count)
+	assert.Equal(t, 12, cfg.HTTP.Level)
+}
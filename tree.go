@@ -0,0 +1,370 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTreeMismatch indicates a document shape, such as a list, that doesn't fit the struct member it targets
+var ErrTreeMismatch = errors.New("document structure does not match the destination type")
+
+// A tree is a generic decoded document, as produced by decoding JSON into an interface{}.
+// Tables are map[string]interface{}, lists are []interface{}, and everything else is a scalar.
+
+// decodeTree overlays a tree onto the struct pointed to by rv. Table keys are matched to struct members
+// by the Go field name or the name in any of the given tags, ignoring case.
+// Unknown keys and members tagged "-" are ignored, scalars are converted with UnmarshalValue,
+// null clears a pointer member, and interface{} members receive the tree itself, with scalars typed by inferNode.
+// When decoding JSON, members whose type implements json.Unmarshaler decode their own part of the document.
+func decodeTree(node interface{}, rv reflect.Value, tags []string) error {
+	if rv.Kind() != reflect.Ptr {
+		return ErrInvalidType
+	}
+	if rv.IsNil() {
+		return ErrNilPointer
+	}
+	return decodeNode(node, rv, tags, nil)
+}
+
+func decodeNode(node interface{}, rv reflect.Value, tags []string, path []string) error {
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		if v := inferNode(node); v != nil {
+			rv.Set(reflect.ValueOf(v))
+		} else {
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return nil
+	}
+
+	if node == nil {
+		// an explicit null clears a pointer member
+		if rv.Kind() == reflect.Ptr && rv.CanSet() {
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return nil
+	}
+
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	if unmarshalsJson(rv, tags) {
+		// the member decodes its own JSON, so it gets its part of the document back as JSON
+		js, err := json.Marshal(node)
+		if err == nil {
+			err = rv.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(js)
+		}
+		if err != nil {
+			return treeError(path, string(js), err)
+		}
+		return nil
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		switch rv.Kind() {
+		case reflect.Struct:
+			for _, k := range keys {
+				sf, ok := findField(rv.Type(), k, tags...)
+				if !ok || skipField(sf, tags) {
+					continue
+				}
+				err := decodeNode(n[k], rv.FieldByIndex(sf.Index), tags, append(path, sf.Name))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+
+		case reflect.Map:
+			typ := rv.Type()
+			if rv.IsNil() {
+				rv.Set(reflect.MakeMap(typ))
+			}
+			for _, k := range keys {
+				key := reflect.New(typ.Key()).Elem()
+				if err := UnmarshalValue(k, key); err != nil {
+					return treeError(path, k, err)
+				}
+				value := reflect.New(typ.Elem()).Elem()
+				if err := decodeNode(n[k], value, tags, append(path, k)); err != nil {
+					return err
+				}
+				rv.SetMapIndex(key, value)
+			}
+			return nil
+		}
+		return treeError(path, "", ErrTreeMismatch)
+
+	case []interface{}:
+		var list reflect.Value
+		switch rv.Kind() {
+		case reflect.Slice:
+			list = reflect.MakeSlice(rv.Type(), len(n), len(n))
+		case reflect.Array:
+			if len(n) != rv.Len() {
+				return treeError(path, "", ErrArrayLength)
+			}
+			list = reflect.New(rv.Type()).Elem()
+		default:
+			return treeError(path, "", ErrTreeMismatch)
+		}
+		for i, item := range n {
+			err := decodeNode(item, list.Index(i), tags, append(path, "["+strconv.Itoa(i)+"]"))
+			if err != nil {
+				return err
+			}
+		}
+		rv.Set(list)
+		return nil
+	}
+
+	s := scalarString(node)
+	if err := UnmarshalValue(s, rv); err != nil {
+		return treeError(path, s, err)
+	}
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unmarshalsJson reports whether rv is decoded from JSON by its own UnmarshalJSON method,
+// which it is when the tree is JSON and no decoder is registered for its type.
+func unmarshalsJson(rv reflect.Value, tags []string) bool {
+	if len(tags) == 0 || tags[0] != jsonTags[0] || !rv.CanAddr() {
+		return false
+	}
+	if _, ok := decoder(rv.Type()); ok {
+		return false
+	}
+	return rv.Addr().Type().Implements(jsonUnmarshalerType)
+}
+
+// inferNode converts a tree for an interface{} member. Tables become map[string]interface{}
+// and lists []interface{}. Untyped text is typed as null, bool, or a number where it looks like one, while
+// strings stay strings, and numbers become int where they fit, or else int64 or float64, whatever the format.
+func inferNode(node interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, child := range n {
+			m[k] = inferNode(child)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(n))
+		for i, item := range n {
+			list[i] = inferNode(item)
+		}
+		return list
+	case plainText:
+		return inferScalar(strings.TrimSpace(string(n)))
+	case json.Number:
+		return inferScalar(string(n))
+	case int64:
+		if int64(int(n)) == n {
+			return int(n)
+		}
+	}
+	return node
+}
+
+// treeError reports a decoding failure at path as a file layer error.
+func treeError(path []string, input string, err error) error {
+	return &SourceError{Layer: LayerFile, Path: strings.Replace(joinPath(path), ".[", "[", -1), Input: input, Err: err}
+}
+
+// scalarString converts a decoded scalar back to text for UnmarshalValue.
+func scalarString(node interface{}) string {
+	switch n := node.(type) {
+	case string:
+		return n
+	case plainText:
+		return string(n)
+	case float64:
+		return strconv.FormatFloat(n, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(n), 'g', -1, 32)
+	case time.Time:
+		return n.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(node)
+}
+
+// traceTree records every struct member that a tree sets, attributing it to file.
+func traceTree(node interface{}, typ reflect.Type, tags []string, path []string, file string, p Provenance) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	table, ok := node.(map[string]interface{})
+	if !ok || typ.Kind() != reflect.Struct {
+		return
+	}
+
+	for k, child := range table {
+		sf, ok := findField(typ, k, tags...)
+		if !ok || child == nil || skipField(sf, tags) {
+			continue
+		}
+
+		fp := append(append([]string{}, path...), sf.Name)
+		if nt := indirectType(sf.Type); isNestedType(nt) && !unmarshalsJson(reflect.New(nt).Elem(), tags) {
+			traceTree(child, sf.Type, tags, fp, file, p)
+			continue
+		}
+		p.Set(joinPath(fp), Origin{Layer: LayerFile, Name: file})
+	}
+}
+
+// treeMap is a table that keeps its keys in struct member order when encoded.
+type treeMap []treeItem
+
+type treeItem struct {
+	Key   string
+	Value interface{}
+}
+
+// MarshalJSON encodes the table as a JSON object with keys in order.
+func (m treeMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, item := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(item.Key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeTree converts rv into a tree of treeMap tables, lists, and scalars.
+// Struct members are named by the first of the given tags that has a name, or by the lower cased Go field name.
+// Members tagged "-" are skipped, as are zero valued members tagged omitempty,
+// and the members of a struct tagged inline are written as if they belonged to its parent.
+func encodeTree(rv reflect.Value, tags []string) interface{} {
+	if !rv.IsValid() {
+		return nil
+	}
+	if _, ok := rv.Interface().(Secret); ok {
+		return redacted
+	}
+	if text, ok := textOf(rv); ok {
+		return text
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return encodeTree(rv.Elem(), tags)
+
+	case reflect.Struct:
+		m := treeMap{}
+		typ := rv.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			if sf.PkgPath != "" || skipField(sf, tags) {
+				continue
+			}
+			fv := rv.Field(i)
+			if omitEmpty(sf, tags) && fv.IsZero() {
+				continue
+			}
+			if inline, ok := encodeTree(fv, tags).(treeMap); ok && hasTagOption(sf, tags, "inline") {
+				m = append(m, inline...)
+				continue
+			}
+			m = append(m, treeItem{Key: keyName(sf, tags), Value: encodeTree(fv, tags)})
+		}
+		return m
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		m := treeMap{}
+		iter := rv.MapRange()
+		for iter.Next() {
+			m = append(m, treeItem{Key: fmt.Sprint(iter.Key().Interface()), Value: encodeTree(iter.Value(), tags)})
+		}
+		sort.Slice(m, func(i, j int) bool { return m[i].Key < m[j].Key })
+		return m
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = encodeTree(rv.Index(i), tags)
+		}
+		return list
+	}
+
+	return rv.Interface()
+}
+
+// keyName returns the document key for a struct member: the name from the first of tags that has one, or the lower cased Go field name.
+func keyName(sf reflect.StructField, tags []string) string {
+	for _, tag := range tags {
+		if name := tagName(sf, tag); name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(sf.Name)
+}
+
+// skipField reports whether the first of tags present on the struct member is "-".
+func skipField(sf reflect.StructField, tags []string) bool {
+	for _, tag := range tags {
+		if value, ok := sf.Tag.Lookup(tag); ok {
+			return value == "-"
+		}
+	}
+	return false
+}
+
+// omitEmpty reports whether any of tags on the struct member carries the omitempty option.
+func omitEmpty(sf reflect.StructField, tags []string) bool {
+	return hasTagOption(sf, tags, "omitempty")
+}
+
+// hasTagOption reports whether any of tags on the struct member carries option, as in `yaml:",inline"`.
+func hasTagOption(sf reflect.StructField, tags []string, option string) bool {
+	for _, tag := range tags {
+		for _, opt := range strings.Split(sf.Tag.Get(tag), ",")[1:] {
+			if opt == option {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTree struct {
+	Limits map[string]int
+	Ptr    *SubNested
+	Hidden string `yaml:"-"`
+}
+
+// tables decode into maps and allocate nil pointers on the way
+func TestDecodeTree(t *testing.T) {
+	tree := map[string]interface{}{
+		"limits": map[string]interface{}{"cpu": json.Number("2"), "mem": "512"},
+		"ptr":    map[string]interface{}{"level": 3.0},
+		"hidden": "nope",
+	}
+
+	cfg := testTree{}
+	err := decodeTree(tree, reflect.ValueOf(&cfg), []string{"yaml"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"cpu": 2, "mem": 512}, cfg.Limits)
+	assert.Equal(t, 3, cfg.Ptr.Level)
+	assert.Equal(t, "", cfg.Hidden)
+}
+
+func TestDecodeTreeNotPointer(t *testing.T) {
+	cfg := testTree{}
+	err := decodeTree(map[string]interface{}{}, reflect.ValueOf(cfg), nil)
+	assert.Equal(t, ErrInvalidType, err)
+
+	var nilCfg *testTree
+	err = decodeTree(map[string]interface{}{}, reflect.ValueOf(nilCfg), nil)
+	assert.Equal(t, ErrNilPointer, err)
+}
+
+// struct members keep their order and map keys are sorted
+func TestEncodeTree(t *testing.T) {
+	cfg := testTree{Limits: map[string]int{"mem": 512, "cpu": 2}, Hidden: "nope"}
+	tree := encodeTree(reflect.ValueOf(&cfg), []string{"yaml"})
+	expected := treeMap{
+		{Key: "limits", Value: treeMap{{Key: "cpu", Value: 2}, {Key: "mem", Value: 512}}},
+		{Key: "ptr", Value: nil},
+	}
+	assert.Equal(t, expected, tree)
+}
+
+// interface{} members get the same types from every format, and only untyped text is inferred
+func TestInferNodeFormats(t *testing.T) {
+	expected := map[string]interface{}{
+		"count": 10,
+		"rate":  0.5,
+		"label": "10",
+		"flag":  "true",
+		"on":    true,
+		"list":  []interface{}{1, "2"},
+	}
+
+	docs := map[string]func([]byte, interface{}) error{
+		"extra:\n  count: 10\n  rate: 0.5\n  label: \"10\"\n  flag: 'true'\n  on: true\n  list: [1, \"2\"]\n": FromYaml,
+		`{"extra": {"count": 10, "rate": 0.5, "label": "10", "flag": "true", "on": true, "list": [1, "2"]}}`:  FromJson,
+		"[extra]\ncount = 10\nrate = 0.5\nlabel = \"10\"\nflag = \"true\"\non = true\nlist = [1, \"2\"]\n":    FromToml,
+	}
+	for doc, from := range docs {
+		cfg := struct{ Extra interface{} }{}
+		err := from([]byte(doc), &cfg)
+		assert.Nil(t, err, doc)
+		assert.Equal(t, expected, cfg.Extra, doc)
+	}
+}
@@ -19,11 +19,17 @@ var (
 	ErrBadMap = errors.New("string for a map must be comma separated list of key:value")
 	// ErrUnsupportedType indicates that Unmarshal doesn't support that type
 	ErrUnsupportedType = errors.New("cannot unmarshal unsupported type")
+	// ErrUnclosedQuote indicates a quoted slice item, map key or value, or struct value without its closing quote
+	ErrUnclosedQuote = errors.New("quoted value is missing its closing quote")
+	// ErrArrayLength indicates the s parameter has a different number of items than the array it is for
+	ErrArrayLength = errors.New("string for an array must have exactly as many items as the array")
+	// ErrBadStruct indicates the s parameter is not a list of key=value pairs or a {key: value} object
+	ErrBadStruct = errors.New("string for a struct must be semicolon separated key=value pairs or a {key: value} object")
 )
 
 // Unmarshal attempts to convert string 's' into a value of type 'v'.
 // It infers the type from v itself, and uses the appropriate conversion routine.
-// This only supports the common basic types, slices, and maps, but not structs.
+// This supports the common basic types, slices, arrays, maps, and structs.
 //	Type                                    Examples
 //	-----------------------------------     ---------------------------------------------
 //	string                                  "", "some string"
@@ -32,8 +38,22 @@ var (
 //	int, int8, int16, int32, int64          "12", "-77"
 //	uint, uint8, uint16, uint32, uint64     "42", "0xDEADBEEF"
 //	float32, float64                        "345.71, "-3.14159"
-//	slice                                   "super, duper", "12, 20, 36"
-//	map                                     "good:26, bad:37, ugly:55", "1:true, 7:false"
+//	complex64, complex128                   "1+2i", "-3.5i", "(2-1i)"
+//	slice                                   "super, duper", "12, 20, 36", `["a,b", 'c,d', C:\tmp]`
+//	array                                   "1, 2, 3" for a [3]int, which must get exactly 3 items
+//	map                                     "good:26, bad:37, ugly:55", "1:true, 7:false", "web:http://a:80"
+//	struct                                  "host=a;port=80", "{host: a, port: 80}", `{"sub": {"level": 3}}`
+//	interface{}                             "true", "42", "2.5", "text", "[1, b]", "{a: 1}", "null"
+// An interface{} member gets a bool, int, float64, string, []interface{}, or map[string]interface{}, whichever the text looks like.
+// Pointer members are allocated when they are nil, and set back to nil by the text null.
+// Types with a decoder from RegisterDecoder, or that implement encoding.TextUnmarshaler or flag.Value, use that instead.
+// Struct keys are matched to members just like the key paths of FromArguments.
+// Slice items and map pairs are split on unquoted commas, and a map key from its value on the first unquoted colon.
+// Items may be wrapped in double quotes, which allow Go escapes, or single quotes, which are literal.
+// A quote only opens quoted text at the start of an item, so O'Brien needs no quoting.
+// A backslash outside double quotes is an ordinary character, so Windows paths need no quoting.
+// Struct members tagged `sep:";"` or `kvsep:"="` use those separators instead when set by FromArguments,
+// the environment, or a flat file.
 func Unmarshal(s string, v interface{}) error {
 	rv := reflect.ValueOf(v)
 	return UnmarshalValue(s, rv)
@@ -43,12 +63,65 @@ func Unmarshal(s string, v interface{}) error {
 // The type is inferred from the type of rv. This has the same functionality as Unmarshal()
 // except that it takes a reflect.Value instead of an interface.
 func UnmarshalValue(s string, rv reflect.Value) error {
+	return unmarshalValue(s, rv, defaultSeparators)
+}
+
+// nullValue sets a pointer member back to nil.
+const nullValue = "null"
+
+// separators split the items of a slice or map, and the key from the value in each map item.
+type separators struct {
+	list string
+	kv   string
+}
+
+var defaultSeparators = separators{list: ",", kv: ":"}
+
+// fieldSeparators returns the separators for a struct member, as overridden by its sep and kvsep tags.
+func fieldSeparators(sf reflect.StructField) separators {
+	seps := defaultSeparators
+	if sep := sf.Tag.Get("sep"); sep != "" {
+		seps.list = sep
+	}
+	if kvsep := sf.Tag.Get("kvsep"); kvsep != "" {
+		seps.kv = kvsep
+	}
+	return seps
+}
+
+// unmarshalField converts s into the struct member rv described by sf, honoring its sep and kvsep tags.
+func unmarshalField(s string, rv reflect.Value, sf reflect.StructField) error {
+	return unmarshalValue(s, rv, fieldSeparators(sf))
+}
+
+func unmarshalValue(s string, rv reflect.Value, seps separators) error {
+	if rv.Kind() == reflect.Ptr && rv.CanSet() {
+		// a pointer member is cleared by null, and otherwise allocated when needed
+		if s == nullValue {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if _, ok := decoder(rv.Type()); ok {
+			_, err := decode(s, rv)
+			return err
+		}
+		if rv.IsNil() {
+			nv := reflect.New(rv.Type().Elem())
+			if err := unmarshalValue(s, nv.Elem(), seps); err != nil {
+				return err
+			}
+			rv.Set(nv)
+			return nil
+		}
+		return unmarshalValue(s, rv.Elem(), seps)
+	}
+
 	if rv.Kind() == reflect.Ptr {
 		if rv.IsNil() {
 			return ErrNilPointer
 		}
 		// if rv is a pointer, dereference into its inner type
-		rv = rv.Elem()
+		return unmarshalValue(s, rv.Elem(), seps)
 	}
 
 	// skip over a value if it cannot be written
@@ -56,6 +129,10 @@ func UnmarshalValue(s string, rv reflect.Value) error {
 		return ErrCannotSetValue
 	}
 
+	if ok, err := decode(s, rv); ok {
+		return err
+	}
+
 	kind := rv.Kind()
 	typ := rv.Type()
 
@@ -99,12 +176,49 @@ func UnmarshalValue(s string, rv reflect.Value) error {
 		}
 		rv.SetFloat(f)
 
+	case kind == reflect.Complex64 || kind == reflect.Complex128:
+		c, err := strconv.ParseComplex(s, typ.Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetComplex(c)
+
+	case kind == reflect.Array:
+		items, err := splitList(s, seps.list)
+		if err != nil {
+			return err
+		}
+		if len(items) != typ.Len() {
+			return ErrArrayLength
+		}
+		array := reflect.New(typ).Elem()
+		for i, item := range items {
+			err := UnmarshalValue(item, array.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		rv.Set(array)
+
+	case kind == reflect.Interface && typ.NumMethod() == 0:
+		v, err := inferValue(s, seps)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			rv.Set(reflect.Zero(typ))
+		} else {
+			rv.Set(reflect.ValueOf(v))
+		}
+
 	case kind == reflect.Slice:
-		sp := strings.Split(s, ",")
-		slice := reflect.MakeSlice(typ, len(sp), len(sp))
-		for i, t := range sp {
-			c := strings.TrimSpace(t)
-			err := UnmarshalValue(c, slice.Index(i))
+		items, err := splitList(s, seps.list)
+		if err != nil {
+			return err
+		}
+		slice := reflect.MakeSlice(typ, len(items), len(items))
+		for i, item := range items {
+			err := UnmarshalValue(item, slice.Index(i))
 			if err != nil {
 				return err
 			}
@@ -112,20 +226,34 @@ func UnmarshalValue(s string, rv reflect.Value) error {
 		rv.Set(slice)
 
 	case kind == reflect.Map:
-		items := strings.Split(s, ",")
+		pairs, err := splitTopLevel(trimBrackets(s, '{', '}'), []string{seps.list}, -1)
+		if err != nil {
+			return err
+		}
 		m := reflect.MakeMap(typ)
-		for _, pair := range items {
-			kv := strings.Split(pair, ":")
-			if len(kv)%2 != 0 {
+		for _, pair := range pairs {
+			kv, err := splitTopLevel(pair, []string{seps.kv}, 2)
+			if err != nil {
+				return err
+			}
+			if len(kv) != 2 {
 				return ErrBadMap
 			}
+			key, err := unquoteToken(kv[0])
+			if err != nil {
+				return err
+			}
 			k := reflect.New(typ.Key()).Elem()
-			err := UnmarshalValue(strings.TrimSpace(kv[0]), k)
+			err = UnmarshalValue(key, k)
+			if err != nil {
+				return err
+			}
+			value, err := unquoteToken(kv[1])
 			if err != nil {
 				return err
 			}
 			v := reflect.New(typ.Elem()).Elem()
-			err = UnmarshalValue(strings.TrimSpace(kv[1]), v)
+			err = UnmarshalValue(value, v)
 			if err != nil {
 				return err
 			}
@@ -133,9 +261,339 @@ func UnmarshalValue(s string, rv reflect.Value) error {
 		}
 		rv.Set(m)
 
+	case kind == reflect.Struct:
+		return unmarshalStruct(s, rv)
+
 	default:
 		return ErrUnsupportedType
 	}
 
 	return nil
 }
+
+// unmarshalStruct sets the members of the struct rv named in s, leaving the others alone.
+// A braced object separates its pairs with commas or semicolons and its keys from values with colons or equals signs,
+// and may nest objects and [lists]. Without braces, pairs are separated only by semicolons
+// so that values can hold comma separated lists.
+func unmarshalStruct(s string, rv reflect.Value) error {
+	pairs, err := structPairs(s)
+	if err != nil {
+		return err
+	}
+	for _, kv := range pairs {
+		fv, sf, _, store, err := resolveField(rv.Addr().Interface(), kv[0])
+		if err != nil {
+			return err
+		}
+		value := kv[1]
+		switch fv.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Struct:
+			// these split value themselves
+		default:
+			if value, err = unquoteToken(value); err != nil {
+				return err
+			}
+		}
+		if err := unmarshalField(value, fv, sf); err != nil {
+			return err
+		}
+		store()
+	}
+	return nil
+}
+
+// structPairs splits the text of a struct value into its unquoted keys and their trimmed, still quoted values.
+func structPairs(s string) ([][2]string, error) {
+	s = strings.TrimSpace(s)
+	seps := []string{";"}
+	if strings.HasPrefix(s, "{") {
+		if !strings.HasSuffix(s, "}") {
+			return nil, ErrBadStruct
+		}
+		s, seps = s[1:len(s)-1], []string{",", ";"}
+	}
+
+	items, err := splitTopLevel(s, seps, -1)
+	if err != nil {
+		return nil, err
+	}
+	var pairs [][2]string
+	for _, item := range items {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		kv, err := splitTopLevel(item, []string{":", "="}, 2)
+		if err != nil {
+			return nil, err
+		}
+		if len(kv) != 2 {
+			return nil, ErrBadStruct
+		}
+		key, err := unquoteToken(kv[0])
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]string{key, strings.TrimSpace(kv[1])})
+	}
+	return pairs, nil
+}
+
+// structMembers returns the paths, relative to a member of type typ, of the members that the text s sets
+// when it is a struct value, including those of nested struct values. It returns nil for any other type.
+func structMembers(s string, typ reflect.Type) []string {
+	typ = indirectType(typ)
+	if !isNestedType(typ) {
+		return nil
+	}
+	pairs, err := structPairs(s)
+	if err != nil {
+		return nil
+	}
+
+	scratch := reflect.New(typ)
+	var paths []string
+	for _, kv := range pairs {
+		fv, _, path, _, err := resolveField(scratch.Interface(), kv[0])
+		if err != nil {
+			continue
+		}
+		paths = append(paths, path)
+		for _, sub := range structMembers(kv[1], fv.Type()) {
+			paths = append(paths, path+"."+sub)
+		}
+	}
+	return paths
+}
+
+// splitList splits a slice value into its unquoted items, allowing the whole list to be wrapped in [brackets].
+func splitList(s string, sep string) ([]string, error) {
+	raw, err := splitTopLevel(trimBrackets(s, '[', ']'), []string{sep}, -1)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]string, len(raw))
+	for i, r := range raw {
+		if items[i], err = unquoteToken(r); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// trimBrackets removes one pair of surrounding open and close characters, along with the space around them.
+func trimBrackets(s string, open, close byte) string {
+	t := strings.TrimSpace(s)
+	if len(t) >= 2 && t[0] == open && t[len(t)-1] == close {
+		return t[1 : len(t)-1]
+	}
+	return s
+}
+
+// splitTopLevel splits s at the seps that aren't quoted or inside braces or brackets,
+// into at most max parts, or all of them if max is negative. The parts are returned raw, for unquoteToken.
+// Only a quote at the start of an item opens quoted text, so one inside a word, as in O'Brien, is an ordinary character.
+func splitTopLevel(s string, seps []string, max int) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case (c == '"' || c == '\'') && opensQuote(s, i, seps):
+			quote = c
+			continue
+		case c == '{' || c == '[':
+			depth++
+			continue
+		case c == '}' || c == ']':
+			depth--
+			continue
+		case depth != 0 || len(parts) == max-1:
+			continue
+		}
+		for _, sep := range seps {
+			if strings.HasPrefix(s[i:], sep) {
+				parts = append(parts, s[start:i])
+				start = i + len(sep)
+				i = start - 1
+				break
+			}
+		}
+	}
+	if quote != 0 {
+		return nil, ErrUnclosedQuote
+	}
+	return append(parts, s[start:]), nil
+}
+
+// unquoteToken turns a raw item from splitTopLevel into its value. Space around it is dropped,
+// double quoted text honors Go escapes, and single quoted text, like anything outside quotes, is literal.
+// Quotes that don't start an item, such as the one in O'Brien, are kept as they are.
+// A braced or bracketed item is returned as is, for a nested value.
+func unquoteToken(raw string) (string, error) {
+	t := strings.TrimSpace(raw)
+	if t != "" && (t[0] == '{' || t[0] == '[') {
+		return t, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		if (c == '"' || c == '\'') && !opensQuote(t, i, nil) {
+			b.WriteByte(c)
+			continue
+		}
+		switch c {
+		case '"':
+			end := i + 1
+			for ; end < len(t) && t[end] != '"'; end++ {
+				if t[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(t) {
+				return "", ErrUnclosedQuote
+			}
+			u, err := strconv.Unquote(t[i : end+1])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(u)
+			i = end
+		case '\'':
+			end := strings.IndexByte(t[i+1:], '\'')
+			if end < 0 {
+				return "", ErrUnclosedQuote
+			}
+			b.WriteString(t[i+1 : i+1+end])
+			i += end + 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// opensQuote reports whether the quote at s[i] opens quoted text, which it does only at the start of an item:
+// when nothing but space comes before it, or the text before it ends with one of seps, a bracket, or a pair separator.
+func opensQuote(s string, i int, seps []string) bool {
+	before := strings.TrimRight(s[:i], " \t")
+	if before == "" || strings.IndexByte("{[,;:=", before[len(before)-1]) >= 0 {
+		return true
+	}
+	for _, sep := range seps {
+		if strings.HasSuffix(before, sep) {
+			return true
+		}
+	}
+	return false
+}
+
+// inferValue converts s for an interface{} member, choosing its type from the text.
+// true and false (in any case) are a bool, whole numbers an int (or int64 when too large for an int),
+// other numbers a float64, null is nil, and anything else is a string. A [list] is a []interface{}
+// and a {table} a map[string]interface{}, whose items are inferred in turn unless they are quoted.
+func inferValue(s string, seps separators) (interface{}, error) {
+	t := strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]"):
+		list := []interface{}{}
+		if strings.TrimSpace(t[1:len(t)-1]) == "" {
+			return list, nil
+		}
+		items, err := splitTopLevel(t[1:len(t)-1], []string{seps.list}, -1)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			v, err := inferItem(item, seps)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+
+	case strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}"):
+		m := map[string]interface{}{}
+		if strings.TrimSpace(t[1:len(t)-1]) == "" {
+			return m, nil
+		}
+		pairs, err := splitTopLevel(t[1:len(t)-1], []string{seps.list}, -1)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			kv, err := splitTopLevel(pair, []string{seps.kv}, 2)
+			if err != nil {
+				return nil, err
+			}
+			if len(kv) != 2 {
+				return nil, ErrBadMap
+			}
+			key, err := unquoteToken(kv[0])
+			if err != nil {
+				return nil, err
+			}
+			if m[key], err = inferItem(kv[1], seps); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	}
+	v := inferScalar(t)
+	if _, ok := v.(string); ok {
+		// keep the text exactly as given, like a string member would
+		return s, nil
+	}
+	return v, nil
+}
+
+// inferScalar types a single trimmed value as inferValue does, apart from lists and tables.
+// Anything that isn't null, a bool or a number is returned unchanged.
+func inferScalar(t string) interface{} {
+	switch {
+	case t == nullValue:
+		return nil
+	case strings.EqualFold(t, "true"):
+		return true
+	case strings.EqualFold(t, "false"):
+		return false
+	case !strings.ContainsAny(t, "0123456789"):
+		// not a number, even if ParseFloat would take it, like NaN
+		return t
+	}
+
+	if i, err := strconv.ParseInt(t, 10, 64); err == nil {
+		if int64(int(i)) == i {
+			return int(i)
+		}
+		return i
+	}
+	if f, err := strconv.ParseFloat(t, 64); err == nil {
+		return f
+	}
+	return t
+}
+
+// inferItem infers the value of a raw list item or table value. Quotes make it a string,
+// and are removed from strings just as for a []string item.
+func inferItem(raw string, seps separators) (interface{}, error) {
+	t := strings.TrimSpace(raw)
+	if t != "" && (t[0] == '"' || t[0] == '\'') {
+		return unquoteToken(t)
+	}
+	v, err := inferValue(t, seps)
+	if s, ok := v.(string); ok && err == nil {
+		return unquoteToken(s)
+	}
+	return v, err
+}
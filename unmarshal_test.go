@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -317,7 +318,7 @@ func TestUnmarshalInvalidSlice(t *testing.T) {
 
 // non-parseable map because of invalid key:value pair separator
 func TestUnmarshalInvalidMapSeparator(t *testing.T) {
-	s := "first:false; second:1"
+	s := "first=false, second=1"
 	d := map[string]bool{}
 	err := Unmarshal(s, &d)
 	assert.NotNil(t, err)
@@ -343,18 +344,252 @@ func TestUnmarshalInvalidMapValue(t *testing.T) {
 	assert.ObjectsAreEqual(map[string]bool{}, d)
 }
 
-// type struct is not supported
+// quoted and escaped items keep their separators
+func TestUnmarshalSliceQuoted(t *testing.T) {
+	d := []string{}
+	err := Unmarshal(`"a, b", 'c\d', 'e,f', "tab\t" ,  g `, &d)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a, b", `c\d`, "e,f", "tab\t", "g"}, d)
+
+	// backslashes outside double quotes are kept
+	err = Unmarshal(`C:\tmp,D:\x\,E:\`, &d)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`C:\tmp`, `D:\x\`, `E:\`}, d)
+
+	err = Unmarshal(`[x, "y"]`, &d)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"x", "y"}, d)
+
+	assert.Equal(t, ErrUnclosedQuote, Unmarshal(`"a, b`, &d))
+}
+
+// only a quote at the start of an item opens quoted text, so apostrophes inside words are kept
+func TestUnmarshalSliceApostrophe(t *testing.T) {
+	d := []string{}
+	err := Unmarshal(`O'Brien,Smith`, &d)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"O'Brien", "Smith"}, d)
+
+	err = Unmarshal(`it's, 'a,b', say "hi", rock'n'roll`, &d)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"it's", "a,b", `say "hi"`, "rock'n'roll"}, d)
+
+	m := map[string]string{}
+	err = Unmarshal(`owner:O'Brien, motto:'a, b'`, &m)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"owner": "O'Brien", "motto": "a, b"}, m)
+
+	c := TestEnvironmentPrefix{}
+	err = FromArguments([]string{"address=O'Brien"}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, "O'Brien", c.Address)
+}
+
+// a map value may hold the key:value separator, and keys may be quoted
+func TestUnmarshalMapURLs(t *testing.T) {
+	d := map[string]string{}
+	err := Unmarshal(`web:http://a:80, "api:v2":'http://b:80/x?q=1,2'`, &d)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"web": "http://a:80", "api:v2": "http://b:80/x?q=1,2"}, d)
+}
+
+type unmarshalSeparators struct {
+	Hosts  []string          `sep:";"`
+	Labels map[string]string `sep:";" kvsep:"="`
+}
+
+// sep and kvsep tags change the separators for a member
+func TestUnmarshalFieldSeparators(t *testing.T) {
+	d := unmarshalSeparators{}
+	err := FromArguments([]string{"hosts=a,1;b,2", "labels=env=prod;team=a,b"}, &d)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a,1", "b,2"}, d.Hosts)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "a,b"}, d.Labels)
+
+	d = unmarshalSeparators{}
+	err = Unmarshal("{hosts: [x;y], labels: {a=1;b=2}}", &d)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"x", "y"}, d.Hosts)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, d.Labels)
+}
+
+// nil pointer members are allocated, and null sets them back to nil
+func TestUnmarshalPointerMembers(t *testing.T) {
+	d := struct {
+		Limit *int
+		Name  **string
+		Tags  *[]string
+	}{}
+	rv := reflect.ValueOf(&d).Elem()
+
+	assert.Nil(t, UnmarshalValue("5", rv.Field(0)))
+	assert.Equal(t, 5, *d.Limit)
+	assert.Nil(t, UnmarshalValue("6", rv.Field(0)))
+	assert.Equal(t, 6, *d.Limit)
+	assert.Nil(t, UnmarshalValue("null", rv.Field(0)))
+	assert.Nil(t, d.Limit)
+
+	assert.Nil(t, UnmarshalValue("deep", rv.Field(1)))
+	assert.Equal(t, "deep", **d.Name)
+
+	assert.Nil(t, UnmarshalValue("a,b", rv.Field(2)))
+	assert.Equal(t, []string{"a", "b"}, *d.Tags)
+
+	// a failed conversion leaves the pointer nil
+	d.Limit = nil
+	assert.NotNil(t, UnmarshalValue("many", rv.Field(0)))
+	assert.Nil(t, d.Limit)
+
+	// a pointer passed to Unmarshal is a destination, not a member
+	limit := 1
+	p := &limit
+	assert.Nil(t, Unmarshal("null", &p))
+	assert.Nil(t, p)
+	assert.Nil(t, Unmarshal("7", &p))
+	assert.Equal(t, 7, *p)
+}
+
+type unmarshalServer struct {
+	Host    string
+	Port    int `yaml:"p"`
+	Tags    []string
+	Limits  map[string]int
+	Timeout time.Duration
+	Sub     struct {
+		Level int
+	}
+}
+
+// structs take key=value pairs or an inline object
 func TestUnmarshalStruct(t *testing.T) {
-	s := "true, time is good, 23"
-	type test struct {
-		b bool
-		s string
-		i int
+	d := unmarshalServer{Host: "kept"}
+	err := Unmarshal("port=80; tags=a,b; sub.level=3", &d)
+	assert.Nil(t, err)
+	assert.Equal(t, "kept", d.Host)
+	assert.Equal(t, 80, d.Port)
+	assert.Equal(t, []string{"a", "b"}, d.Tags)
+	assert.Equal(t, 3, d.Sub.Level)
+
+	d = unmarshalServer{}
+	err = Unmarshal(`{host: "a,b", p: 8080, tags: [x, y], limits: {x: 1, y: 2}, timeout: 1m, sub: {level: 4}}`, &d)
+	assert.Nil(t, err)
+	assert.Equal(t, "a,b", d.Host)
+	assert.Equal(t, 8080, d.Port)
+	assert.Equal(t, []string{"x", "y"}, d.Tags)
+	assert.Equal(t, map[string]int{"x": 1, "y": 2}, d.Limits)
+	assert.Equal(t, time.Minute, d.Timeout)
+	assert.Equal(t, 4, d.Sub.Level)
+
+	d = unmarshalServer{}
+	err = Unmarshal(`{"host":"example.com:443","sub":{"level":5}}`, &d)
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com:443", d.Host)
+	assert.Equal(t, 5, d.Sub.Level)
+}
+
+func TestUnmarshalInvalidStruct(t *testing.T) {
+	d := unmarshalServer{}
+	assert.Equal(t, ErrBadStruct, Unmarshal("true, time is good, 23", &d))
+	assert.Equal(t, ErrBadStruct, Unmarshal("{host: a", &d))
+	assert.Equal(t, ErrUnknownField, Unmarshal("bogus=1", &d))
+	assert.NotNil(t, Unmarshal("{port: many}", &d))
+}
+
+func TestUnmarshalArray(t *testing.T) {
+	d := [3]float64{}
+	assert.Nil(t, Unmarshal("0.5, 1, 2.5", &d))
+	assert.Equal(t, [3]float64{0.5, 1, 2.5}, d)
+	assert.Nil(t, Unmarshal("[1,2,3]", &d))
+	assert.Equal(t, [3]float64{1, 2, 3}, d)
+
+	// a wrong length or bad item leaves the array alone
+	assert.Equal(t, ErrArrayLength, Unmarshal("1,2", &d))
+	assert.Equal(t, ErrArrayLength, Unmarshal("1,2,3,4", &d))
+	assert.NotNil(t, Unmarshal("1,2,many", &d))
+	assert.Equal(t, [3]float64{1, 2, 3}, d)
+
+	grid := [2][2]int{}
+	assert.Nil(t, Unmarshal("[[1, 2], [3, 4]]", &grid))
+	assert.Equal(t, [2][2]int{{1, 2}, {3, 4}}, grid)
+}
+
+func TestUnmarshalComplex(t *testing.T) {
+	d := complex128(0)
+	assert.Nil(t, Unmarshal("1.5+2i", &d))
+	assert.Equal(t, complex(1.5, 2), d)
+	assert.Nil(t, Unmarshal("(3-4i)", &d))
+	assert.Equal(t, complex(3, -4), d)
+	assert.Nil(t, Unmarshal("7", &d))
+	assert.Equal(t, complex(7, 0), d)
+
+	d64 := complex64(0)
+	assert.Nil(t, Unmarshal("-1i", &d64))
+	assert.Equal(t, complex64(complex(0, -1)), d64)
+
+	assert.NotNil(t, Unmarshal("1+", &d))
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	tests := []struct {
+		s    string
+		want interface{}
+	}{
+		{"true", true},
+		{"FALSE", false},
+		{"42", 42},
+		{"-7", -7},
+		{"18446744073709551616", 18446744073709551616.0},
+		{"2.5", 2.5},
+		{"1e3", 1000.0},
+		{"hello", "hello"},
+		{"NaN", "NaN"},
+		{"null", nil},
+		{"[1, two, 3.5]", []interface{}{1, "two", 3.5}},
+		{`["42", 'a,b', C:\tmp]`, []interface{}{"42", "a,b", `C:\tmp`}},
+		{"[]", []interface{}{}},
+		{"{rate: 0.5, on: true, tags: [a, b]}", map[string]interface{}{"rate": 0.5, "on": true, "tags": []interface{}{"a", "b"}}},
 	}
-	d := test{}
-	err := Unmarshal(s, &d)
-	assert.NotNil(t, err)
-	assert.Equal(t, ErrUnsupportedType, err)
-	expected := test{false, "", 0}
-	assert.ElementsMatch(t, expected, d)
+	for _, test := range tests {
+		var d interface{} = "previous"
+		assert.Nil(t, Unmarshal(test.s, &d), test.s)
+		assert.Equal(t, test.want, d, test.s)
+	}
+
+	var d interface{}
+	assert.Equal(t, ErrBadMap, Unmarshal("{rate}", &d))
+	assert.Equal(t, ErrUnclosedQuote, Unmarshal(`["a]`, &d))
+}
+
+func TestUnmarshalKindsInFiles(t *testing.T) {
+	yml := `
+origin: [0.5, 1, 2]
+extra:
+  steps: 10
+  label: "10"
+  dt: 0.01
+  on: true
+  modes: [fast, 3]
+`
+	cfg := struct {
+		Origin [3]float64
+		Extra  interface{}
+	}{}
+	err := FromYaml([]byte(yml), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, [3]float64{0.5, 1, 2}, cfg.Origin)
+	assert.Equal(t, map[string]interface{}{
+		"steps": 10,
+		"label": "10",
+		"dt":    0.01,
+		"on":    true,
+		"modes": []interface{}{"fast", 3},
+	}, cfg.Extra)
+
+	// yaml.v2 checks array lengths itself
+	err = FromYaml([]byte("origin: [1, 2]"), &cfg)
+	assert.Contains(t, err.Error(), "want 3 elements but got 2")
+
+	err = FromJson([]byte(`{"origin": [1, 2]}`), &cfg)
+	assert.Equal(t, ErrArrayLength, err.(*SourceError).Err)
+	assert.Equal(t, "Origin", err.(*SourceError).Path)
 }
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrRequired indicates a required member was left at its zero value
+	ErrRequired = errors.New("value is required")
+	// ErrTooSmall indicates a number below its min, or a string, slice, or map shorter than its min
+	ErrTooSmall = errors.New("value is below the minimum")
+	// ErrTooLarge indicates a number above its max, or a string, slice, or map longer than its max
+	ErrTooLarge = errors.New("value is above the maximum")
+	// ErrNotOneOf indicates a value that isn't one of the allowed choices
+	ErrNotOneOf = errors.New("value is not one of the allowed choices")
+	// ErrInvalidURL indicates a value that isn't an absolute URL
+	ErrInvalidURL = errors.New("value is not an absolute URL")
+	// ErrInvalidHostPort indicates a value that isn't a host:port pair
+	ErrInvalidHostPort = errors.New("value is not a host:port pair")
+	// ErrUnknownRule indicates a validate tag rule that isn't supported
+	ErrUnknownRule = errors.New("unknown validation rule")
+)
+
+// ValidationError describes a struct member whose loaded value breaks one of its validate tag rules.
+// Origin tells which source supplied the bad value, and has an empty Layer if no source set it.
+type ValidationError struct {
+	Path   string
+	Rule   string
+	Value  interface{}
+	Origin Origin
+	Err    error
+}
+
+// Error formats the violation as "path: value from origin: rule: cause".
+func (e *ValidationError) Error() string {
+	from := "not set by any source"
+	if e.Origin.Layer != "" {
+		from = "from " + e.Origin.String()
+	}
+	return fmt.Sprintf("%s: %v %s: %s: %v", e.Path, e.Value, from, e.Rule, e.Err)
+}
+
+// Unwrap returns the cause so errors.Is can test for ErrRequired and the like.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks every member of the struct pointed to by v against the comma separated rules
+// in its "validate:" struct tag, returning all violations together as Errors of *ValidationError.
+// Load, LoadE, and every Loader run it automatically after all layers are applied,
+// skipping the members of subcommand sections that weren't chosen on the command line.
+//	required       must not be the zero value
+//	min=N, max=N   bounds a number, or the length of a string, slice, or map
+//	oneof=a|b|c    must be one of the listed values
+//	url            must be an absolute URL such as http://example.com
+//	hostport       must be a host:port pair such as localhost:8080
+// The oneof, url, and hostport rules accept an empty value, so combine them with required to forbid one.
+func Validate(v interface{}) error {
+	return validate(v, nil)
+}
+
+func validate(v interface{}, p Provenance) error {
+	var errs Errors
+	walkFields(v, func(f field) error {
+		tag, ok := f.sf.Tag.Lookup("validate")
+		if !ok || p != nil && !commandChosen(f, p) {
+			return nil
+		}
+
+		for _, rule := range strings.Split(tag, ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "" {
+				continue
+			}
+			if err := checkRule(f.value, rule); err != nil {
+				errs = append(errs, &ValidationError{
+					Path:   f.Path(),
+					Rule:   rule,
+					Value:  f.value.Interface(),
+					Origin: p[f.Path()],
+					Err:    err,
+				})
+			}
+		}
+		return nil
+	})
+	return errs.err()
+}
+
+// checkRule tests a single validate tag rule against rv.
+func checkRule(rv reflect.Value, rule string) error {
+	name, arg := rule, ""
+	if eq := strings.Index(rule, "="); eq >= 0 {
+		name, arg = rule[:eq], rule[eq+1:]
+	}
+
+	switch name {
+	case "required":
+		if rv.IsZero() {
+			return ErrRequired
+		}
+
+	case "min", "max":
+		cmp, err := compareBound(rv, arg)
+		if err != nil {
+			return err
+		}
+		if name == "min" && cmp < 0 {
+			return ErrTooSmall
+		}
+		if name == "max" && cmp > 0 {
+			return ErrTooLarge
+		}
+
+	case "oneof":
+		s := fmt.Sprint(rv.Interface())
+		if s == "" {
+			return nil
+		}
+		for _, choice := range strings.Split(arg, "|") {
+			if s == choice {
+				return nil
+			}
+		}
+		return ErrNotOneOf
+
+	case "url":
+		if rv.Kind() != reflect.String || rv.Len() == 0 {
+			return nil
+		}
+		u, err := url.Parse(rv.String())
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return ErrInvalidURL
+		}
+
+	case "hostport":
+		if rv.Kind() != reflect.String || rv.Len() == 0 {
+			return nil
+		}
+		_, port, err := net.SplitHostPort(rv.String())
+		if err != nil {
+			return ErrInvalidHostPort
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return ErrInvalidHostPort
+		}
+
+	default:
+		return ErrUnknownRule
+	}
+
+	return nil
+}
+
+// compareBound returns -1, 0, or 1 as rv is less than, equal to, or greater than bound.
+// Strings, slices, and maps compare their length, and numbers parse bound as their own type, so "1m" works for a time.Duration.
+func compareBound(rv reflect.Value, bound string) (int, error) {
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		n, err := strconv.Atoi(bound)
+		if err != nil {
+			return 0, err
+		}
+		return compareInts(int64(rv.Len()), int64(n)), nil
+	}
+
+	b := reflect.New(rv.Type())
+	if err := UnmarshalValue(bound, b); err != nil {
+		return 0, err
+	}
+	b = b.Elem()
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareInts(rv.Int(), b.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch {
+		case rv.Uint() < b.Uint():
+			return -1, nil
+		case rv.Uint() > b.Uint():
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Float32, reflect.Float64:
+		switch {
+		case rv.Float() < b.Float():
+			return -1, nil
+		case rv.Float() > b.Float():
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, ErrUnsupportedType
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
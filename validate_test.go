@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestValidate struct {
+	Address string        `validate:"required,url"`
+	Listen  string        `validate:"hostport"`
+	Mode    string        `validate:"oneof=debug|info"`
+	Level   int           `validate:"min=1,max=100"`
+	Timeout time.Duration `validate:"min=1s,max=1h"`
+	Ratio   float64       `validate:"max=0.5"`
+	Tags    []string      `validate:"max=2"`
+}
+
+func validConfig() TestValidate {
+	return TestValidate{
+		Address: "http://example.com/",
+		Listen:  "localhost:8080",
+		Mode:    "info",
+		Level:   50,
+		Timeout: time.Minute,
+		Ratio:   0.25,
+		Tags:    []string{"a", "b"},
+	}
+}
+
+func TestValidateGood(t *testing.T) {
+	cfg := validConfig()
+	assert.Nil(t, Validate(&cfg))
+
+	// optional format rules accept empty values
+	cfg.Listen = ""
+	cfg.Mode = ""
+	assert.Nil(t, Validate(&cfg))
+}
+
+// every violation is reported
+func TestValidateBad(t *testing.T) {
+	cfg := TestValidate{
+		Listen:  "localhost",
+		Mode:    "trace",
+		Level:   -5,
+		Timeout: time.Millisecond,
+		Ratio:   0.75,
+		Tags:    []string{"a", "b", "c"},
+	}
+
+	err := Validate(&cfg)
+	errs, ok := err.(Errors)
+	assert.True(t, ok, "Expected Errors")
+
+	var got []string
+	for _, e := range errs {
+		verr := e.(*ValidationError)
+		got = append(got, verr.Path+" "+verr.Rule)
+	}
+	assert.Equal(t, []string{
+		"Address required",
+		"Listen hostport",
+		"Mode oneof=debug|info",
+		"Level min=1",
+		"Timeout min=1s",
+		"Ratio max=0.5",
+		"Tags max=2",
+	}, got)
+	assert.True(t, errors.Is(errs[0], ErrRequired))
+	assert.True(t, errors.Is(errs[3], ErrTooSmall))
+	assert.True(t, errors.Is(errs[5], ErrTooLarge))
+}
+
+func TestValidateRules(t *testing.T) {
+	cfg := validConfig()
+	cfg.Address = "example.com"
+	cfg.Listen = "localhost:http"
+	cfg.Level = 101
+	errs := Validate(&cfg).(Errors)
+	assert.Equal(t, 3, len(errs))
+	assert.True(t, errors.Is(errs[0], ErrInvalidURL))
+	assert.True(t, errors.Is(errs[1], ErrInvalidHostPort))
+	assert.True(t, errors.Is(errs[2], ErrTooLarge))
+
+	bad := struct {
+		Name string `validate:"shiny"`
+	}{}
+	err := Validate(&bad)
+	assert.True(t, errors.Is(err.(Errors)[0], ErrUnknownRule))
+}
+
+// Load validates after every layer, naming the source of each bad value
+func TestLoadValidates(t *testing.T) {
+	os.Args = []string{"test", "level=-5"}
+
+	cfg := validConfig()
+	err := LoadE("bogus_file_name.yml", &cfg)
+	errs, ok := err.(Errors)
+	assert.True(t, ok, "Expected Errors")
+	assert.Equal(t, 1, len(errs))
+	verr := errs[0].(*ValidationError)
+	assert.Equal(t, "Level", verr.Path)
+	assert.Equal(t, -5, verr.Value)
+	assert.Equal(t, Origin{Layer: LayerArguments, Name: "level=-5", Index: 0}, verr.Origin)
+	assert.Equal(t, "Level: -5 from args[0] level=-5: min=1: value is below the minimum", verr.Error())
+
+	cfg = validConfig()
+	cfg.Address = ""
+	os.Args = []string{"test"}
+	err = LoadE("bogus_file_name.yml", &cfg)
+	assert.Equal(t, "Address:  not set by any source: required: value is required", err.Error())
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultPollInterval is how often a polling Watcher checks its file
+	defaultPollInterval = time.Second
+	// settleDelay lets a burst of writes to the file finish before it is reloaded
+	settleDelay = 100 * time.Millisecond
+)
+
+// Watcher keeps a configuration struct up to date with its config file.
+// Whenever the file changes, it builds a fresh struct through the same layers as Load,
+// and delivers it to subscribers only when the result differs from the current one.
+// Delivered structs are shared between subscribers and must be treated as read only.
+type Watcher struct {
+	file    string
+	typ     reflect.Type
+	opts    []Option
+	current atomic.Value
+
+	mu      sync.Mutex
+	funcs   []func(interface{})
+	chans   []chan interface{}
+	onError func(error)
+
+	reloadMu  sync.Mutex
+	events    chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closer    func() error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Watch fills in the specified struct like LoadE, then watches the config file and rebuilds the
+// configuration whenever it changes. It uses file system notifications where the platform supports
+// them, and otherwise polls the file. The initial struct is not modified by later reloads.
+func Watch(file string, v interface{}, opts ...Option) (*Watcher, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidType
+	}
+	if file == "" {
+		file = defaultConfigFile
+	}
+
+	if err := LoadE(file, v, opts...); err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		file:   file,
+		typ:    rv.Elem().Type(),
+		opts:   opts,
+		events: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	initial := reflect.New(w.typ)
+	initial.Elem().Set(rv.Elem())
+	w.current.Store(initial.Interface())
+
+	o := newOptions(opts)
+	var err error
+	if o.pollInterval == 0 {
+		w.closer, err = notifyChanges(file, w.events)
+	}
+	if o.pollInterval != 0 || err != nil {
+		interval := o.pollInterval
+		if interval <= 0 {
+			interval = defaultPollInterval
+		}
+		w.closer = pollChanges(file, interval, w.events)
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Current returns a pointer to the most recently loaded configuration struct.
+func (w *Watcher) Current() interface{} {
+	return w.current.Load()
+}
+
+// Subscribe calls fn with a pointer to each newly loaded configuration struct.
+// Callbacks run on the goroutine that reloaded, which is the Watcher's own unless Reload was called directly.
+func (w *Watcher) Subscribe(fn func(cfg interface{})) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.funcs = append(w.funcs, fn)
+}
+
+// Changes returns a channel that receives a pointer to each newly loaded configuration struct.
+// A slow reader only misses intermediate versions, never the latest one.
+// The channel is closed by Close.
+func (w *Watcher) Changes() <-chan interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ch := make(chan interface{}, 1)
+	w.chans = append(w.chans, ch)
+	return ch
+}
+
+// OnError calls fn whenever a reload fails. The current configuration is kept when that happens.
+func (w *Watcher) OnError(fn func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = fn
+}
+
+// Reload rebuilds the configuration immediately, as if the file had changed,
+// and returns any error from loading it. Subscribers may call it themselves.
+func (w *Watcher) Reload() error {
+	next, err := w.reload()
+	if next != nil {
+		// deliver without holding reloadMu, so a subscriber can reload again
+		w.publish(next)
+	}
+	return err
+}
+
+// reload loads the next configuration and makes it current, returning nil if it failed or is unchanged.
+func (w *Watcher) reload() (interface{}, error) {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	next := reflect.New(w.typ).Interface()
+	if err := LoadE(w.file, next, w.opts...); err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(next, w.current.Load()) {
+		return nil, nil
+	}
+
+	w.current.Store(next)
+	return next, nil
+}
+
+// Close stops watching the file and closes every channel returned by Changes.
+// Calling it again does nothing and returns the first call's result.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		w.closeErr = w.closer()
+		<-w.done
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for _, ch := range w.chans {
+			close(ch)
+		}
+		w.chans = nil
+	})
+	return w.closeErr
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.events:
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(settleDelay):
+		}
+		// fold any events from the burst into this reload
+		select {
+		case <-w.events:
+		default:
+		}
+
+		if err := w.Reload(); err != nil {
+			w.mu.Lock()
+			onError := w.onError
+			w.mu.Unlock()
+			if onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (w *Watcher) publish(cfg interface{}) {
+	w.mu.Lock()
+	if w.current.Load() != cfg {
+		// a later reload has already replaced it, and delivers its own version
+		w.mu.Unlock()
+		return
+	}
+	funcs := append([]func(interface{}){}, w.funcs...)
+	for _, ch := range w.chans {
+		// replace an unread older version with this one
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- cfg
+	}
+	w.mu.Unlock()
+
+	for _, fn := range funcs {
+		fn(cfg)
+	}
+}
+
+// signal records a pending change without blocking.
+func signal(events chan<- struct{}) {
+	select {
+	case events <- struct{}{}:
+	default:
+	}
+}
+
+// pollChanges checks the file's size and modification time every interval, signalling events when they change.
+// It returns a function that stops polling.
+func pollChanges(file string, interval time.Duration, events chan<- struct{}) func() error {
+	stop := make(chan struct{})
+	last := fileState(file)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			if state := fileState(file); state != last {
+				last = state
+				signal(events)
+			}
+		}
+	}()
+
+	return func() error {
+		close(stop)
+		return nil
+	}
+}
+
+type statState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func fileState(file string) statState {
+	info, err := os.Stat(file)
+	if err != nil {
+		return statState{}
+	}
+	return statState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
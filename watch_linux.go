@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// notifyChanges uses inotify on the file's directory to signal events whenever the file is written,
+// created, replaced, or removed. Watching the directory catches editors that save by renaming a new file into place.
+// It returns a function that stops watching.
+func notifyChanges(file string, events chan<- struct{}) (func() error, error) {
+	dir, name := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	const mask = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+		syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM | syscall.IN_ATTRIB
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// a non-blocking descriptor lets the runtime poller interrupt Read when the file is closed
+	f := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+				ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+				start := off + syscall.SizeofInotifyEvent
+				off = start + int(ev.Len)
+				if off > n {
+					break
+				}
+				if strings.TrimRight(string(buf[start:off]), "\x00") == name {
+					signal(events)
+				}
+			}
+		}
+	}()
+
+	return f.Close, nil
+}
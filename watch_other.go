@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package config
+
+import (
+	"errors"
+)
+
+// notifyChanges is only implemented with inotify on Linux, so Watch falls back to polling elsewhere.
+func notifyChanges(file string, events chan<- struct{}) (func() error, error) {
+	return nil, errors.New("file system notifications are not supported on this platform")
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeWatched replaces the contents of a watched test file
+func writeWatched(t *testing.T, name, contents string) {
+	err := ioutil.WriteFile(name, []byte(contents), 0644)
+	assert.Nil(t, err, "Got error trying to write temporary YAML file")
+}
+
+func receive(t *testing.T, ch <-chan interface{}) *TestYaml {
+	select {
+	case cfg := <-ch:
+		return cfg.(*TestYaml)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a reload")
+	}
+	return nil
+}
+
+func testWatch(t *testing.T, opts ...Option) {
+	file, err := ioutil.TempFile(".", "watch_test*.yml")
+	assert.Nil(t, err, "Got error trying to create temporary YAML file")
+	file.Close()
+	defer os.Remove(file.Name())
+	writeWatched(t, file.Name(), "count: 1\n")
+
+	os.Args = []string{"test"}
+	cfg := TestYaml{}
+	w, err := Watch(file.Name(), &cfg, opts...)
+	assert.Nil(t, err)
+	defer w.Close()
+	assert.Equal(t, 1, cfg.Count)
+	assert.Equal(t, 1, w.Current().(*TestYaml).Count)
+
+	// callbacks run after the channels are sent to, so they report back on a channel of their own
+	called := make(chan interface{}, 10)
+	w.Subscribe(func(c interface{}) {
+		called <- c
+	})
+	changes := w.Changes()
+
+	writeWatched(t, file.Name(), "count: 2\n")
+	assert.Equal(t, 2, receive(t, changes).Count)
+	assert.Equal(t, 2, receive(t, called).Count)
+	assert.Equal(t, 2, w.Current().(*TestYaml).Count)
+	assert.Equal(t, 1, cfg.Count, "Expected the initial struct to be left alone")
+
+	// a change that loads the same configuration is not delivered
+	writeWatched(t, file.Name(), "# comment\ncount: 2\n")
+	writeWatched(t, file.Name(), "# comment\ncount: 3\n")
+	assert.Equal(t, 3, receive(t, changes).Count)
+	assert.Equal(t, 3, receive(t, called).Count)
+	assert.Equal(t, 0, len(called))
+}
+
+// edits are picked up through file system notifications
+func TestWatch(t *testing.T) {
+	testWatch(t)
+}
+
+// edits are picked up by polling
+func TestWatchPolling(t *testing.T) {
+	testWatch(t, WithPolling(20*time.Millisecond))
+}
+
+// a broken edit is reported and the last good configuration is kept
+func TestWatchError(t *testing.T) {
+	file, err := ioutil.TempFile(".", "watch_test*.yml")
+	assert.Nil(t, err, "Got error trying to create temporary YAML file")
+	file.Close()
+	defer os.Remove(file.Name())
+	writeWatched(t, file.Name(), "count: 1\n")
+
+	os.Args = []string{"test"}
+	cfg := TestYaml{}
+	w, err := Watch(file.Name(), &cfg, WithPolling(20*time.Millisecond))
+	assert.Nil(t, err)
+
+	errs := make(chan error, 1)
+	w.OnError(func(err error) { errs <- err })
+	changes := w.Changes()
+
+	writeWatched(t, file.Name(), notYml)
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a reload error")
+	}
+	assert.Equal(t, 1, w.Current().(*TestYaml).Count)
+
+	assert.Nil(t, w.Close())
+	_, ok := <-changes
+	assert.False(t, ok, "Expected Close to close the changes channel")
+}
+
+// a subscriber may reload, and closing twice is harmless
+func TestWatchReloadFromSubscriber(t *testing.T) {
+	file, err := ioutil.TempFile(".", "watch_test*.yml")
+	assert.Nil(t, err, "Got error trying to create temporary YAML file")
+	file.Close()
+	defer os.Remove(file.Name())
+	writeWatched(t, file.Name(), "count: 1\n")
+
+	os.Args = []string{"test"}
+	cfg := TestYaml{}
+	w, err := Watch(file.Name(), &cfg, WithPolling(time.Hour))
+	assert.Nil(t, err)
+
+	reloaded := make(chan error, 1)
+	w.Subscribe(func(c interface{}) {
+		if c.(*TestYaml).Count == 2 {
+			reloaded <- w.Reload()
+		}
+	})
+
+	writeWatched(t, file.Name(), "count: 2\n")
+	done := make(chan error, 1)
+	go func() { done <- w.Reload() }()
+	select {
+	case err := <-reloaded:
+		assert.Nil(t, err)
+		assert.Nil(t, <-done)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out reloading from a subscriber")
+	}
+
+	assert.Nil(t, w.Close())
+	assert.Nil(t, w.Close())
+}
+
+func TestWatchInvalid(t *testing.T) {
+	cfg := TestYaml{}
+	_, err := Watch("", cfg)
+	assert.Equal(t, ErrInvalidType, err)
+}